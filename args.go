@@ -0,0 +1,147 @@
+package goflagbuilder
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// MaxArgFileDepth is the number of argument files that ExpandArgs follows
+// within one another before failing, which also stops files that include
+// themselves.
+const MaxArgFileDepth = 16
+
+// ExpandArgs replaces each argument of the form "@path" with the arguments
+// read from the file at path, so that long command lines may be kept in files.
+// It is applied to the arguments before they are parsed:
+//
+//	args, err := goflagbuilder.ExpandArgs(os.Args[1:])
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	flag.CommandLine.Parse(args)
+//
+// Arguments in the file are separated by white space, and are quoted as in a
+// POSIX shell: single quotes take their content literally, and double quotes
+// and backslashes escape the characters that follow.  A "#" at the start of an
+// argument begins a comment to the end of the line.  The file may itself
+// contain "@path" arguments, which are resolved relative to its directory, up
+// to MaxArgFileDepth levels deep.
+//
+// An argument of "@@..." stands for the literal argument "@...".  Arguments
+// after "--" are not expanded.
+func ExpandArgs(args []string) ([]string, error) {
+	expanded, _, err := expandArgs(args, "", 0, false)
+	return expanded, err
+}
+
+// expandArgs expands the arguments read from a file in dir at the given depth.
+// It also reports whether "--" has been seen, after which nothing is expanded.
+func expandArgs(args []string, dir string, depth int, done bool) ([]string, bool, error) {
+	var expanded []string
+	for _, arg := range args {
+		switch {
+		case done || !strings.HasPrefix(arg, "@") || arg == "@":
+			done = done || arg == "--"
+			expanded = append(expanded, arg)
+
+		case strings.HasPrefix(arg, "@@"):
+			expanded = append(expanded, arg[1:])
+
+		default:
+			filename := arg[1:]
+			if dir != "" && !filepath.IsAbs(filename) {
+				filename = filepath.Join(dir, filename)
+			}
+			if depth >= MaxArgFileDepth {
+				return nil, done, fmt.Errorf("argument file %s is nested too deeply", filename)
+			}
+
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return nil, done, err
+			}
+			fileArgs, err := splitArgs(string(data))
+			if err != nil {
+				return nil, done, fmt.Errorf("argument file %s: %w", filename, err)
+			}
+
+			fileArgs, done, err = expandArgs(fileArgs, filepath.Dir(filename), depth+1, done)
+			if err != nil {
+				return nil, done, err
+			}
+			expanded = append(expanded, fileArgs...)
+		}
+	}
+	return expanded, done, nil
+}
+
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+// splitArgs splits s into arguments as a shell would, without expanding
+// variables or globs.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+
+		case c == '#' && !inArg:
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+
+		case c == '\\':
+			i++
+			if i < len(s) && s[i] != '\n' {
+				arg.WriteByte(s[i])
+				inArg = true
+			}
+
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errUnterminatedQuote
+			}
+			arg.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				arg.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errUnterminatedQuote
+			}
+			inArg = true
+
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
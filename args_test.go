@@ -0,0 +1,85 @@
+package goflagbuilder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitArgs(t *testing.T) {
+	suite := []struct {
+		name  string
+		input string
+		args  []string
+	}{
+		{"empty", "", nil},
+		{"whitespace", " -a  1\n\t-b\r\n2 ", []string{"-a", "1", "-b", "2"}},
+		{"comments", "# leading\n-a 1 # trailing\n-b#not-a-comment", []string{"-a", "1", "-b#not-a-comment"}},
+		{"single quotes", `-a 'x y' 'it''s' '\n'`, []string{"-a", "x y", "its", `\n`}},
+		{"double quotes", `"x y" "a \"b\" \\ \$ \n" "line\` + "\n" + `break"`, []string{"x y", `a "b" \ $ \n`, "linebreak"}},
+		{"backslashes", `x\ y \# \'a\' con\` + "\n" + `tinued`, []string{"x y", "#", "'a'", "continued"}},
+		{"adjacent quotes", `-Name="a b"'c'd ""`, []string{"-Name=a bcd", ""}},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			args, err := splitArgs(item.input)
+			require.NoError(t, err)
+			assert.Equal(t, item.args, args)
+		})
+	}
+
+	_, err := splitArgs(`-a "unterminated`)
+	assert.Equal(t, errUnterminatedQuote, err)
+	_, err = splitArgs(`-a 'unterminated`)
+	assert.Equal(t, errUnterminatedQuote, err)
+}
+
+func TestExpandArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"main.args":       "# batch settings\n-Port 1234\n@sub/nested.args\n-Label 'a b'",
+		"sub/nested.args": "-Attrs.a 1\n-Attrs.b 2 @deeper.args",
+		"sub/deeper.args": "-Verbose",
+		"loop.args":       "@loop.args",
+		"bad.args":        "-Label \"oops",
+		"positional.args": "-- @main.args",
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+	}
+
+	at := func(name string) string { return "@" + filepath.Join(dir, name) }
+
+	args, err := ExpandArgs([]string{"-x", at("main.args"), "@@literal", "@", "--", at("main.args")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"-x",
+		"-Port", "1234",
+		"-Attrs.a", "1", "-Attrs.b", "2", "-Verbose",
+		"-Label", "a b",
+		"@literal", "@", "--", at("main.args"),
+	}, args)
+
+	args, err = ExpandArgs([]string{at("positional.args"), at("main.args")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--", "@main.args", at("main.args")}, args)
+
+	_, err = ExpandArgs([]string{at("loop.args")})
+	assert.EqualError(t, err, "argument file "+filepath.Join(dir, "loop.args")+" is nested too deeply")
+
+	_, err = ExpandArgs([]string{at("bad.args")})
+	assert.EqualError(t, err, "argument file "+filepath.Join(dir, "bad.args")+": unterminated quote")
+
+	_, err = ExpandArgs([]string{at("missing.args")})
+	assert.True(t, os.IsNotExist(err), "unexpected error: %v", err)
+}
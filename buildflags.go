@@ -5,9 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"reflect"
+	"strings"
 )
 
-func populateMapFlags(flags FlagSet, prefix string, mapval reflect.Value) error {
+func populateMapFlags(flags FlagSet, envs map[string]string, prefix string, mapval reflect.Value) error {
 	if prefix != "" {
 		prefix += "."
 	}
@@ -28,12 +29,13 @@ func populateMapFlags(flags FlagSet, prefix string, mapval reflect.Value) error
 		if mapKind.valueKind != nil {
 			value := value{
 				value:  mapval,
+				def:    copyValue(elementval),
 				kind:   mapKind,
 				isBool: elementval.Kind() == reflect.Bool,
 			}
 			flags.Var(value, subprefix, "")
 
-		} else if err := recurseBuildFlags(flags, subprefix, elementval); err != nil {
+		} else if err := recurseBuildFlags(flags, envs, subprefix, elementval); err != nil {
 			return err
 		}
 	}
@@ -41,7 +43,7 @@ func populateMapFlags(flags FlagSet, prefix string, mapval reflect.Value) error
 	return nil
 }
 
-func populateStructFlags(flags FlagSet, prefix string, structval reflect.Value) error {
+func populateStructFlags(flags FlagSet, envs map[string]string, prefix string, structval reflect.Value) error {
 	if prefix != "" {
 		prefix += "."
 	}
@@ -88,9 +90,19 @@ func populateStructFlags(flags FlagSet, prefix string, structval reflect.Value)
 			if !elementval.CanSet() {
 				return fmt.Errorf("value of type %s at %s cannot be set", field.Type.String(), subprefix)
 			}
+			value.def = copyValue(elementval)
+
+			value.env = envNames(field.Tag.Get("env"))
+			for _, name := range value.env {
+				if other, ok := envs[name]; ok {
+					return fmt.Errorf("environment variable %s of %s is already used by %s", name, subprefix, other)
+				}
+				envs[name] = subprefix
+			}
+
 			flags.Var(value, subprefix, help)
 
-		} else if err := recurseBuildFlags(flags, subprefix, elementval); err != nil {
+		} else if err := recurseBuildFlags(flags, envs, subprefix, elementval); err != nil {
 			return err
 		}
 	}
@@ -98,7 +110,7 @@ func populateStructFlags(flags FlagSet, prefix string, structval reflect.Value)
 	return nil
 }
 
-func recursePtrFlags(flags FlagSet, prefix string, ptrval reflect.Value) error {
+func recursePtrFlags(flags FlagSet, envs map[string]string, prefix string, ptrval reflect.Value) error {
 	if ptrval.IsNil() {
 		if ptrval.CanSet() {
 			ptrval.Set(reflect.New(ptrval.Type().Elem()))
@@ -107,25 +119,38 @@ func recursePtrFlags(flags FlagSet, prefix string, ptrval reflect.Value) error {
 		}
 	}
 
-	return recurseBuildFlags(flags, prefix, ptrval.Elem())
+	return recurseBuildFlags(flags, envs, prefix, ptrval.Elem())
 }
 
-func recurseBuildFlags(flags FlagSet, prefix string, elementval reflect.Value) error {
+// recurseBuildFlags adds flags for elementval to flags.  The environment
+// variables named by "env" tags are recorded in envs, mapped to their keys.
+func recurseBuildFlags(flags FlagSet, envs map[string]string, prefix string, elementval reflect.Value) error {
 	switch elementval.Kind() {
 	case reflect.Map:
-		return populateMapFlags(flags, prefix, elementval)
+		return populateMapFlags(flags, envs, prefix, elementval)
 
 	case reflect.Struct:
-		return populateStructFlags(flags, prefix, elementval)
+		return populateStructFlags(flags, envs, prefix, elementval)
 
 	case reflect.Interface, reflect.Ptr:
-		return recursePtrFlags(flags, prefix, elementval)
+		return recursePtrFlags(flags, envs, prefix, elementval)
 
 	default:
 		return fmt.Errorf("cannot build flags from type %v for prefix '%s'", elementval.Type(), prefix)
 	}
 }
 
+// envNames splits the comma separated names of an "env" struct tag.
+func envNames(tag string) []string {
+	var names []string
+	for _, name := range strings.Split(tag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // Into populates the given flag set with hierarchical fields from the
 // given object.  It returns a Parser that may be used to read those
 // same flags from a configuration file.
@@ -134,7 +159,7 @@ func Into(flags FlagSet, configuration interface{}) error {
 		return errors.New("cannot build flags from nil")
 	}
 
-	err := recurseBuildFlags(flags, "", reflect.ValueOf(configuration))
+	err := recurseBuildFlags(flags, make(map[string]string), "", reflect.ValueOf(configuration))
 	if err != nil {
 		return err
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"flag"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -293,3 +294,113 @@ func TestInto(t *testing.T) {
 		})
 	}
 }
+
+type removeResetter interface {
+	Remove(string) error
+	Reset() error
+}
+
+func TestRemoveReset(t *testing.T) {
+	conf := &struct {
+		Files []string
+		Count int
+		Attrs map[string][]int
+	}{
+		Files: []string{"a", "b"},
+		Count: 7,
+		Attrs: map[string][]int{"Foo": {1, 2, 1}},
+	}
+
+	flagSet := flag.NewFlagSet("remove reset", flag.ContinueOnError)
+	if err := Into(flagSet, conf); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err := flagSet.Parse([]string{"-Files", "c", "-Count", "9", "-Attrs.Foo", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, conf.Files)
+
+	files := flagSet.Lookup("Files").Value.(removeResetter)
+	assert.NoError(t, files.Remove("a"))
+	assert.Equal(t, []string{"b", "c"}, conf.Files)
+	assert.NoError(t, files.Reset())
+	assert.Equal(t, []string{"a", "b"}, conf.Files)
+	assert.NoError(t, flagSet.Lookup("Files").Value.(interface{ Clear() error }).Clear())
+	assert.Equal(t, []string{}, conf.Files)
+	assert.NoError(t, files.Reset())
+
+	count := flagSet.Lookup("Count").Value.(removeResetter)
+	assert.EqualError(t, count.Remove("9"), "value does not hold multiple elements")
+	assert.NoError(t, count.Reset())
+	assert.Equal(t, 7, conf.Count)
+
+	attrs := flagSet.Lookup("Attrs.Foo").Value.(removeResetter)
+	assert.Equal(t, []int{3}, conf.Attrs["Foo"])
+	assert.NoError(t, flagSet.Lookup("Attrs.Foo").Value.(interface{ Clear() error }).Clear())
+	assert.Equal(t, []int{}, conf.Attrs["Foo"])
+	assert.NoError(t, attrs.Reset())
+	assert.NoError(t, attrs.Remove("1"))
+	assert.Equal(t, []int{2}, conf.Attrs["Foo"])
+}
+
+func TestInto_EnvTag(t *testing.T) {
+	conf := &struct {
+		Port     int    `env:"PORT, HTTP_PORT"`
+		Database string `env:"DATABASE_URL"`
+		Name     string
+	}{}
+
+	flagSet := flag.NewFlagSet("env tag", flag.ContinueOnError)
+	if err := Into(flagSet, conf); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	envNames := func(name string) []string {
+		return flagSet.Lookup(name).Value.(interface{ EnvNames() []string }).EnvNames()
+	}
+	assert.Equal(t, []string{"PORT", "HTTP_PORT"}, envNames("Port"))
+	assert.Equal(t, []string{"DATABASE_URL"}, envNames("Database"))
+	assert.Nil(t, envNames("Name"))
+
+	duplicate := &struct {
+		Port  int `env:"PORT"`
+		Admin struct {
+			Port int `env:"ADMIN_PORT,PORT"`
+		}
+	}{}
+	err := Into(flag.NewFlagSet("duplicate", flag.ContinueOnError), duplicate)
+	assert.EqualError(t, err, "environment variable PORT of Admin.Port is already used by Port")
+}
+
+func TestInto_Time(t *testing.T) {
+	conf := &struct {
+		Started time.Time
+		Seen    []time.Time
+		Timeout time.Duration
+	}{
+		Timeout: time.Second,
+	}
+
+	flagSet := flag.NewFlagSet("time", flag.ContinueOnError)
+	if err := Into(flagSet, conf); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err := flagSet.Parse([]string{
+		"-Started", "2018-10-15T12:30:00Z",
+		"-Seen", "2018-10-16T08:00:00Z", "-Seen", "2018-10-17T08:00:00.5Z",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2018, 10, 15, 12, 30, 0, 0, time.UTC), conf.Started)
+	assert.Equal(t, []time.Time{
+		time.Date(2018, 10, 16, 8, 0, 0, 0, time.UTC),
+		time.Date(2018, 10, 17, 8, 0, 0, 5e8, time.UTC),
+	}, conf.Seen)
+	assert.Equal(t, "2018-10-15T12:30:00Z", flagSet.Lookup("Started").Value.String())
+	assert.Error(t, flagSet.Set("Started", "yesterday"))
+
+	// A time.Duration remains an integer number of nanoseconds.
+	assert.Equal(t, "1000000000", flagSet.Lookup("Timeout").DefValue)
+	assert.NoError(t, flagSet.Set("Timeout", "90"))
+	assert.Equal(t, 90*time.Nanosecond, conf.Timeout)
+}
@@ -0,0 +1,66 @@
+package conf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+)
+
+// RejectRedefinition makes ParseDir fail if a fragment sets a key that an
+// earlier fragment already set.  It has no effect on Parse and ParseFile.
+func RejectRedefinition() Option {
+	return func(o *options) {
+		o.redefine = true
+	}
+}
+
+// ParseDir reads each file in dir whose name ends in ".conf", in lexical
+// order, as ParseFile would, so that later fragments override earlier ones.
+// This suits conf.d directories to which packages add their own fragments.
+// Errors are prefixed with the name of the fragment.  If flagSet is nil, the
+// global flag.CommandLine FlagSet is used.
+func ParseDir(dir string, flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".conf") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	defined := make(map[string]string)
+
+	for _, name := range names {
+		name := name
+		assign := func(o *options) {
+			o.assign = func(key string, line int) error {
+				if previous, ok := defined[key]; ok && previous != name && o.redefine {
+					return fmt.Errorf("key '%s' on line %d was already set by %s", key, line, previous)
+				}
+				defined[key] = name
+				return nil
+			}
+		}
+
+		opts := append(append([]Option(nil), opts...), assign)
+		if err := ParseFile(filepath.Join(dir, name), flagSet, opts...); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFragments(t *testing.T, fragments map[string]string) string {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	if err != nil {
+		t.Fatal("failed to create temp dir:", err)
+	}
+
+	for name, content := range fragments {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal("failed to write fragment:", err)
+		}
+	}
+
+	return dir
+}
+
+func TestParseDir(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"10-base.conf":  "FieldA = base\nFieldB = 1",
+		"20-local.conf": "FieldB = 2",
+		"30-notes.txt":  "FieldB = 3",
+		"05-early.conf": "FieldA = early\nFieldC = true",
+	})
+	defer os.RemoveAll(dir)
+
+	flagSet := makeFlagSet("TestParseDir", map[string]interface{}{
+		"FieldA": "",
+		"FieldB": 0,
+		"FieldC": false,
+	})
+
+	if err := ParseDir(dir, flagSet); err != nil {
+		t.Fatal("failed to parse dir:", err)
+	}
+
+	for key, expected := range map[string]string{"FieldA": "base", "FieldB": "2", "FieldC": "true"} {
+		if value := flagSet.Lookup(key).Value.String(); value != expected {
+			t.Errorf("expected %s to be %q, got %q", key, expected, value)
+		}
+	}
+}
+
+func TestParseDirInvalid(t *testing.T) {
+	suite := []struct {
+		name      string
+		fragments map[string]string
+		opts      []Option
+		err       string
+	}{
+		{
+			name:      "bad fragment",
+			fragments: map[string]string{"10-a.conf": "FieldA = 1", "20-b.conf": "FieldC = 1"},
+			err:       "20-b.conf: unknown key 'FieldC' on line 1, did you mean 'FieldA' or 'FieldB'?",
+		},
+		{
+			name:      "redefinition",
+			fragments: map[string]string{"10-a.conf": "FieldA = 1", "20-b.conf": "FieldB = 1\nFieldA = 2"},
+			opts:      []Option{RejectRedefinition()},
+			err:       "20-b.conf: key 'FieldA' on line 2 was already set by 10-a.conf",
+		},
+		{
+			name:      "redefinition in one fragment",
+			fragments: map[string]string{"10-a.conf": "FieldA = 1\nFieldA = 2", "20-b.conf": "FieldA = 3"},
+			opts:      []Option{RejectRedefinition()},
+			err:       "20-b.conf: key 'FieldA' on line 1 was already set by 10-a.conf",
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			dir := writeFragments(t, item.fragments)
+			defer os.RemoveAll(dir)
+
+			flagSet := makeFlagSet("TestParseDirInvalid", map[string]interface{}{
+				"FieldA": 0,
+				"FieldB": 0,
+			})
+
+			err := ParseDir(dir, flagSet, item.opts...)
+			if err == nil {
+				t.Error("expected parse error")
+			} else if err.Error() != item.err {
+				t.Error("unexpected error:", err.Error())
+			}
+		})
+	}
+
+	if err := ParseDir("/goflagbuilder-bad-test", makeFlagSet("TestParseDirInvalid", nil)); err == nil {
+		t.Error("expected missing directory error")
+	}
+}
@@ -0,0 +1,167 @@
+package conf
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Document is a configuration file held in memory so that individual keys may
+// be read and edited.  Comments, blank lines, and the order of entries are
+// retained, and lines that are not edited are written back exactly as read.
+// Only entries outside of profile sections are read and edited; profile
+// sections are written back unchanged.
+type Document struct {
+	lines []*docLine
+}
+
+type docLine struct {
+	raw     string
+	indent  string
+	profile string
+	entry
+}
+
+// ParseDocument reads the given Reader into a Document.  It reports the same
+// syntax errors as Parse, but does not check keys against any flags.
+func ParseDocument(in io.Reader) (*Document, error) {
+	doc := &Document{}
+
+	var line int
+	var profile string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line++
+
+		str := scanner.Text()
+		e, err := parseLine(str, line)
+		if err != nil {
+			return nil, err
+		}
+		if e.section != "" {
+			profile = e.section
+		}
+
+		doc.lines = append(doc.lines, &docLine{
+			raw:     str,
+			indent:  str[:len(str)-len(strings.TrimLeft(str, " \t"))],
+			profile: profile,
+			entry:   e,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// Keys returns each distinct key in the document, in the order of their first
+// appearance.
+func (d *Document) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, l := range d.lines {
+		if l.key != "" && l.profile == "" && !seen[l.key] {
+			seen[l.key] = true
+			keys = append(keys, l.key)
+		}
+	}
+	return keys
+}
+
+// Get returns the value of the last entry for key and whether any entry was
+// found.  For a key that is only ever assigned with "=", this is the value that
+// takes effect when the document is parsed.
+func (d *Document) Get(key string) (string, bool) {
+	if l := d.last(key); l != nil {
+		return l.value, true
+	}
+	return "", false
+}
+
+// Set changes the last entry for key into an assignment of value, keeping any
+// trailing comment.  If there is no entry for key, one is added to the end.
+func (d *Document) Set(key, value string) {
+	l := d.last(key)
+	if l == nil {
+		d.Add(key, value)
+		return
+	}
+
+	l.op = "="
+	l.value = value
+	l.raw = l.render()
+}
+
+// Add appends a new entry for key after the last entry outside of profile
+// sections, regardless of any existing entries for key.
+func (d *Document) Add(key, value string) {
+	l := &docLine{entry: entry{key: key, op: "=", value: value}}
+	l.raw = l.render()
+
+	// Keep any comments and blank lines leading into the first profile
+	// section with that section.
+	i := 0
+	for i < len(d.lines) && d.lines[i].section == "" {
+		i++
+	}
+	if i == len(d.lines) {
+		d.lines = append(d.lines, l)
+		return
+	}
+	for i > 0 && d.lines[i-1].key == "" {
+		i--
+	}
+
+	d.lines = append(d.lines[:i], append([]*docLine{l}, d.lines[i:]...)...)
+}
+
+// Delete removes every entry for key, and reports whether there were any.
+// Comment lines around the entries are left in place.
+func (d *Document) Delete(key string) bool {
+	var lines []*docLine
+	for _, l := range d.lines {
+		if l.key != key || l.profile != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	found := len(lines) != len(d.lines)
+	d.lines = lines
+	return found
+}
+
+// WriteTo writes the document to the given Writer.
+func (d *Document) WriteTo(out io.Writer) (int64, error) {
+	var n int64
+	for _, l := range d.lines {
+		c, err := io.WriteString(out, l.raw+"\n")
+		n += int64(c)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (d *Document) last(key string) *docLine {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if d.lines[i].key == key && d.lines[i].profile == "" {
+			return d.lines[i]
+		}
+	}
+	return nil
+}
+
+func (l *docLine) render() string {
+	str := l.indent + l.op + l.key
+	if l.op != "!" && l.op != "" {
+		str = l.indent + l.key + " " + l.op + " " + quote(l.value)
+	}
+	if l.comment != "" {
+		str += " " + l.comment
+	}
+	return str
+}
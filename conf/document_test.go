@@ -0,0 +1,174 @@
+package conf
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+const testDocument = `# Database settings
+Database.Host = localhost
+	Database.Port = 5432   # default port
+
+# Features
+Feature = a
+Feature = b # second
+Name = "quoted # value"
+`
+
+const testOperatorDocument = `Files += a.log
+Files -= b.log
+!Name # back to default
+`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if buf.String() != testDocument {
+		t.Errorf("document changed\nexpected: %q\n  actual: %q", testDocument, buf.String())
+	}
+}
+
+func TestDocumentOperators(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(testOperatorDocument))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	doc.Set("Name", "sushi")
+	doc.Add("Files", "c.log")
+
+	expected := `Files += a.log
+Files -= b.log
+Name = sushi # back to default
+Files = c.log
+`
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("document not equal\nexpected: %q\n  actual: %q", expected, buf.String())
+	}
+}
+
+func TestDocumentEdit(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expectedKeys := "Database.Host,Database.Port,Feature,Name"
+	if keys := strings.Join(doc.Keys(), ","); keys != expectedKeys {
+		t.Errorf("keys not equal\nexpected: %s\n  actual: %s", expectedKeys, keys)
+	}
+
+	if v, ok := doc.Get("Feature"); !ok || v != "b" {
+		t.Errorf("unexpected value for Feature: %q %v", v, ok)
+	}
+	if v, ok := doc.Get("Name"); !ok || v != "quoted # value" {
+		t.Errorf("unexpected value for Name: %q %v", v, ok)
+	}
+	if _, ok := doc.Get("Missing"); ok {
+		t.Error("found missing key")
+	}
+
+	doc.Set("Database.Port", "5433")
+	doc.Set("Feature", " c ")
+	doc.Set("Timeout", "30")
+	doc.Add("Timeout", "40")
+	if !doc.Delete("Database.Host") {
+		t.Error("did not delete Database.Host")
+	}
+	if doc.Delete("Missing") {
+		t.Error("deleted missing key")
+	}
+
+	expected := `# Database settings
+	Database.Port = 5433 # default port
+
+# Features
+Feature = a
+Feature = " c " # second
+Name = "quoted # value"
+Timeout = 30
+Timeout = 40
+`
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("document not equal\nexpected: %q\n  actual: %q", expected, buf.String())
+	}
+
+	flagSet := flag.NewFlagSet("edit", flag.ContinueOnError)
+	port := flagSet.Int("Database.Port", 0, "")
+	feature := flagSet.String("Feature", "", "")
+	flagSet.String("Name", "", "")
+	timeout := flagSet.Int("Timeout", 0, "")
+
+	if err := Parse(&buf, flagSet); err != nil {
+		t.Fatal("error parsing edited document:", err)
+	}
+	if *port != 5433 || *feature != " c " || *timeout != 40 {
+		t.Errorf("unexpected values %d %q %d", *port, *feature, *timeout)
+	}
+}
+
+func TestParseDocumentInvalid(t *testing.T) {
+	_, err := ParseDocument(strings.NewReader("Foo = 1\nBar baz"))
+	if err == nil || err.Error() != "line 2 has no key" {
+		t.Error("unexpected error:", err)
+	}
+}
+
+func TestDocumentProfiles(t *testing.T) {
+	input := `Host = localhost
+
+# Production
+[profile.prod]
+Host = db.example.com
+`
+	doc, err := ParseDocument(strings.NewReader(input))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if v, ok := doc.Get("Host"); !ok || v != "localhost" {
+		t.Errorf("unexpected value for Host: %q %v", v, ok)
+	}
+
+	doc.Set("Host", "db.local")
+	doc.Set("Port", "5432")
+	if doc.Delete("Missing") {
+		t.Error("deleted missing key")
+	}
+
+	expected := `Host = db.local
+Port = 5432
+
+# Production
+[profile.prod]
+Host = db.example.com
+`
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("document not equal\nexpected: %q\n  actual: %q", expected, buf.String())
+	}
+}
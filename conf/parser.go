@@ -1,17 +1,72 @@
 /*
 
 Package conf provides a simple configuration file format that reads values
-backed by a flag.FlagSet.
+backed by a flag.FlagSet, or any other FlagSet.
 
 The configuration file format is single lines of "Key=Value", and comments
 marked by "#". Everything after the equals sign is assigned to the key.
-Whitespace is trimmed. Comments can be escaped by \#.
+Whitespace is trimmed. Comments can be escaped by \#. A value may instead be
+enclosed in double quotes, in which case it is unquoted following Go's string
+literal syntax and may contain any character.
 
 For example:
 
 	# A full-line comment
 	Foo = 10
 	Bar.Baz = hello
+	Bar.Qux = "  # not a comment\n"
+
+Quoting changes how some existing files are read.  A value that starts with
+a double quote was once taken literally, quotes and all, so that
+
+	Key = "x"
+
+set Key to "x" where it now sets it to x.  Text after the closing quote,
+other than a comment, is now an error.
+
+Values holding several elements, such as slices, accumulate one element per
+assignment.  The first "Key = Value" for such a key in a file, or in the
+selected profile, replaces the elements it held before, if its flag value
+implements Clearer as those built by goflagbuilder.Into do.  So a file written
+by Write reads back unchanged, whatever defaults the slice had.
+"Key += Value" appends to such a value explicitly, and fails for values that
+hold a single element.  "Key -= Value" removes each matching
+element, and requires a flag value implementing Remover.  A line "!Key" resets
+a key other than a boolean to its default, which is useful in a file layered
+over another:
+
+	Files += extra.log
+	Files -= debug.log
+	!Bar.Baz
+
+A key on its own sets a boolean flag to true, as on the command line.  The
+key may be negated by a "no-" or "!" prefix to set it to false instead:
+
+	Verbose
+	no-Debug
+	!Trace
+
+By default a key that does not match any flag is an error. The IgnoreUnknown,
+OnUnknown, and CollectUnknown options relax this, for example so that a
+shared file may carry keys that only newer binaries know about.
+
+A file may carry overrides for particular environments in profile sections,
+which run to the next section or the end of the file.  With the Profile option,
+the entries of the named profile are applied over the rest of the file;
+without it, profile sections are skipped:
+
+	Database.Host = localhost
+
+	[profile.prod]
+	Database.Host = db.example.com
+
+ParseDir reads the fragments of a conf.d directory in lexical order, each
+overriding the last.  With RejectRedefinition, a fragment may not set a key
+that an earlier fragment already set.
+
+Write emits the values of a flag set in this format.  To change individual
+keys in an existing file without disturbing its comments and layout, read it
+with ParseDocument, edit the Document, and write it back.
 
 */
 package conf
@@ -22,62 +77,206 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/suggest"
 )
 
+// UnknownKeyError describes a key in a configuration file that does not match
+// any flag. Suggestions holds the names of similarly spelled flags, if any.
+// Bare is set for a key on its own, without a value, which is reported as a
+// line with no key as it may not have been meant as one.
+type UnknownKeyError struct {
+	Key         string
+	Line        int
+	Bare        bool
+	Suggestions []string
+}
+
+func (e *UnknownKeyError) Error() string {
+	msg := fmt.Sprintf("unknown key '%s' on line %d", e.Key, e.Line)
+	if e.Bare {
+		msg = fmt.Sprintf("line %d has no key", e.Line)
+	}
+	if s := suggest.Format(e.Suggestions); s != "" {
+		msg += ", " + s
+	}
+	return msg
+}
+
+// Remover is implemented by flag values that hold several elements, such as
+// the slices handled by goflagbuilder.  It is required by "Key -= Value".
+type Remover interface {
+	Remove(value string) error
+}
+
+// Clearer is implemented by flag values that hold several elements and can
+// remove them all.  It is used by the first "Key = Value" for a key.
+type Clearer interface {
+	Clear() error
+}
+
+// Resetter is implemented by flag values that can restore their default.  It
+// is used by "!Key", which otherwise sets the flag's DefValue.
+type Resetter interface {
+	Reset() error
+}
+
+// Option configures optional behavior of Parse and ParseFile.
+type Option func(*options)
+
+type options struct {
+	unknown     func(*UnknownKeyError) error
+	redefine    bool
+	profile     string
+	profileOnly bool
+
+	// assign, if set, is called before each key is assigned.
+	assign func(key string, line int) error
+
+	// assigned holds the keys assigned so far in the file or profile.
+	assigned map[string]bool
+}
+
+// IgnoreUnknown skips keys that do not match any flag.
+func IgnoreUnknown() Option {
+	return OnUnknown(func(*UnknownKeyError) {})
+}
+
+// OnUnknown calls fn for each key that does not match any flag, and then
+// continues parsing. This may be used to log a warning.
+func OnUnknown(fn func(err *UnknownKeyError)) Option {
+	return func(o *options) {
+		o.unknown = func(err *UnknownKeyError) error {
+			fn(err)
+			return nil
+		}
+	}
+}
+
+// CollectUnknown appends each key that does not match any flag to keys, and
+// continues parsing.
+func CollectUnknown(keys *[]*UnknownKeyError) Option {
+	return OnUnknown(func(err *UnknownKeyError) {
+		*keys = append(*keys, err)
+	})
+}
+
+// Profile applies the entries of the sections "[profile.NAME]" for the given
+// name, which follow and so override all entries outside of profile sections.
+func Profile(name string) Option {
+	return func(o *options) {
+		o.profile = name
+		o.profileOnly = false
+	}
+}
+
+// OnlyProfile applies only the entries of the sections "[profile.NAME]" for the
+// given name, skipping the rest of the file.  This allows a caller to tell
+// which values were set by the profile.
+func OnlyProfile(name string) Option {
+	return func(o *options) {
+		o.profile = name
+		o.profileOnly = true
+	}
+}
+
+func buildOptions(opts []Option) options {
+	o := options{
+		unknown: func(err *UnknownKeyError) error { return err },
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 // Parse reads the given Reader line by line and parses key/value pairs, setting
 // values to matching flags in the given flagset.  If flagSet is nil, then the
 // global flag.CommandLine FlagSet is used.
-func Parse(in io.Reader, flagSet *flag.FlagSet) error {
-	if flagSet == nil {
+func Parse(in io.Reader, flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
 		flagSet = flag.CommandLine
 	}
 
+	o := buildOptions(opts)
+	o.assigned = make(map[string]bool)
+
 	var line int
+	var section string
+	var inProfile bool
 	scanner := bufio.NewScanner(in)
 	for scanner.Scan() {
 		line++
 
-		str := scanner.Text()
+		e, err := parseLine(scanner.Text(), line)
+		if err != nil {
+			return err
+		}
+		if e.section != "" {
+			section = e.section
 
-		index := strings.Index(str, "#")
-		if index != -1 {
-			if index == 0 || str[index-1] != '\\' {
-				str = str[:index]
+			// The profile replaces slices anew, as a file layered
+			// over the rest would.
+			if section == o.profile && !inProfile {
+				inProfile = true
+				o.assigned = make(map[string]bool)
 			}
+			continue
 		}
-
-		str = strings.TrimSpace(str)
-		if str == "" {
+		if e.key == "" {
 			continue
 		}
 
-		index = strings.Index(str, "=")
-		if index < 0 {
-			return fmt.Errorf("line %d has no key", line)
+		// Profile sections follow every other entry, so a profile is
+		// applied over the rest of the file.
+		if section != "" && section != o.profile || section == "" && o.profileOnly {
+			continue
 		}
+		if err := o.set(flagSet, e, line); err != nil {
+			return err
+		}
+	}
 
-		key := strings.TrimSpace(str[:index])
-		value := strings.TrimSpace(str[index+1:])
+	return scanner.Err()
+}
 
-		flag := flagSet.Lookup(key)
-		if flag == nil {
-			return fmt.Errorf("unknown key '%s' on line %d", key, line)
+// set assigns the value of e, read from the given line, to its flag.
+func (o *options) set(flagSet FlagSet, e entry, line int) error {
+	flag, e := lookup(flagSet, e)
+	if flag == nil {
+		err := &UnknownKeyError{
+			Key:         e.key,
+			Line:        line,
+			Bare:        e.op == "",
+			Suggestions: suggest.Closest(e.key, flagutil.Names(flagSet)),
 		}
-		if err := flag.Value.Set(value); err != nil {
+		return o.unknown(err)
+	}
+	if o.assign != nil {
+		if err := o.assign(flag.Name, line); err != nil {
 			return err
 		}
 	}
-
-	return scanner.Err()
+	if !o.assigned[flag.Name] {
+		o.assigned[flag.Name] = true
+		if e.op == "=" {
+			if err := flagutil.Clear(flag.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return apply(flag, e, line)
 }
 
 // ParseFile reads the file indicated by filename line by line and parses
 // key/value pairs, setting values to matching flags in the given flagset. It is
 // identical to calling Parse on a File opened from filename. If flagSet is nil,
 // the global flag.CommandLine FlagSet is used.
-func ParseFile(filename string, flagSet *flag.FlagSet) error {
-	if flagSet == nil {
+func ParseFile(filename string, flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
 		flagSet = flag.CommandLine
 	}
 
@@ -87,5 +286,162 @@ func ParseFile(filename string, flagSet *flag.FlagSet) error {
 	}
 	defer in.Close()
 
-	return Parse(in, flagSet)
+	return Parse(in, flagSet, opts...)
+}
+
+// entry is a single parsed line. Blank and comment lines have an empty key,
+// and bare keys have an empty operator.  Section headers have only a section,
+// the name of their profile.
+type entry struct {
+	key     string
+	op      string
+	value   string
+	comment string
+	section string
+}
+
+// parseLine splits a line into its key, operator, value, and any trailing
+// comment.
+func parseLine(str string, line int) (entry, error) {
+	index := strings.IndexAny(str, "#=")
+	if index < 0 || str[index] == '#' {
+		text := commentless(str)
+		trimmed := strings.TrimSpace(text)
+
+		comment := strings.TrimSpace(str[len(text):])
+
+		if strings.HasPrefix(trimmed, "[") {
+			name := strings.TrimSuffix(strings.TrimPrefix(trimmed, "[profile."), "]")
+			if !strings.HasPrefix(trimmed, "[profile.") || !strings.HasSuffix(trimmed, "]") ||
+				name == "" || strings.ContainsAny(name, " \t[]") {
+				return entry{}, fmt.Errorf("line %d has an invalid section", line)
+			}
+			return entry{section: name, comment: comment}, nil
+		}
+
+		if strings.HasPrefix(trimmed, "!") && len(trimmed) > 1 {
+			return entry{
+				key:     strings.TrimSpace(trimmed[1:]),
+				op:      "!",
+				comment: comment,
+			}, nil
+		}
+		if strings.ContainsAny(trimmed, " \t") {
+			return entry{}, fmt.Errorf("line %d has no key", line)
+		}
+		return entry{key: trimmed, comment: comment}, nil
+	}
+
+	op, keyEnd := "=", index
+	if index > 0 && (str[index-1] == '+' || str[index-1] == '-') {
+		op, keyEnd = str[index-1:index+1], index-1
+	}
+
+	key := strings.TrimSpace(str[:keyEnd])
+	rest := strings.TrimSpace(str[index+1:])
+	if key == "" {
+		return entry{}, fmt.Errorf("line %d has no key", line)
+	}
+
+	if !strings.HasPrefix(rest, `"`) {
+		value := commentless(rest)
+		return entry{
+			key:     key,
+			op:      op,
+			value:   strings.Replace(strings.TrimSpace(value), `\#`, "#", -1),
+			comment: rest[len(value):],
+		}, nil
+	}
+
+	end := 1
+	for ; end < len(rest); end++ {
+		if rest[end] == '\\' {
+			end++
+		} else if rest[end] == '"' {
+			break
+		}
+	}
+	if end >= len(rest) {
+		return entry{}, fmt.Errorf("unterminated quoted value on line %d", line)
+	}
+
+	value, err := strconv.Unquote(rest[:end+1])
+	if err != nil {
+		return entry{}, fmt.Errorf("invalid quoted value on line %d", line)
+	}
+
+	trailing := rest[end+1:]
+	if strings.TrimSpace(commentless(trailing)) != "" {
+		return entry{}, fmt.Errorf("unexpected text after quoted value on line %d", line)
+	}
+
+	return entry{
+		key:     key,
+		op:      op,
+		value:   value,
+		comment: strings.TrimSpace(trailing),
+	}, nil
+}
+
+// lookup finds the flag for e.  A bare key may name a boolean flag, possibly
+// negated with a "no-" prefix, in which case e is rewritten as an assignment.
+func lookup(flagSet FlagSet, e entry) (*flag.Flag, entry) {
+	f := flagSet.Lookup(e.key)
+	if e.op != "" {
+		return f, e
+	}
+
+	if f != nil && flagutil.IsBool(f.Value) {
+		return f, entry{key: e.key, op: "=", value: "true"}
+	}
+
+	if key := strings.TrimPrefix(e.key, "no-"); f == nil && key != e.key {
+		if f := flagSet.Lookup(key); f != nil && flagutil.IsBool(f.Value) {
+			return f, entry{key: key, op: "=", value: "false"}
+		}
+	}
+
+	return f, e
+}
+
+// apply assigns the value of e to f according to its operator.
+func apply(f *flag.Flag, e entry, line int) error {
+	switch e.op {
+	case "":
+		return fmt.Errorf("key '%s' on line %d has no value", e.key, line)
+
+	case "+=":
+		if !flagutil.Multiple(f.Value) {
+			return fmt.Errorf("cannot append to key '%s' on line %d", e.key, line)
+		}
+		return f.Value.Set(e.value)
+
+	case "-=":
+		remover, ok := f.Value.(Remover)
+		if !ok {
+			return fmt.Errorf("cannot remove from key '%s' on line %d", e.key, line)
+		}
+		return remover.Remove(e.value)
+
+	case "!":
+		if flagutil.IsBool(f.Value) {
+			return f.Value.Set("false")
+		}
+		if resetter, ok := f.Value.(Resetter); ok {
+			return resetter.Reset()
+		}
+		return f.Value.Set(f.DefValue)
+	}
+
+	return f.Value.Set(e.value)
+}
+
+// commentless returns str up to the first "#" that is not escaped.
+func commentless(str string) string {
+	for i := 0; i < len(str); i++ {
+		if str[i] == '#' && (i == 0 || str[i-1] != '\\') {
+			return str[:i]
+		}
+	}
+	return str
 }
@@ -20,6 +20,9 @@ func makeFlagSet(name string, flags map[string]interface{}) *flag.FlagSet {
 		case string:
 			flagSet.String(k, v, "")
 
+		case bool:
+			flagSet.Bool(k, v, "")
+
 		default:
 			panic("unexpected type in test")
 		}
@@ -169,6 +172,30 @@ func TestParseValid(t *testing.T) {
 				"FieldB": 7,
 			},
 		},
+		{
+			name:  "parse escaped comment",
+			input: `FieldA=sushi \# 1 # Banananana`,
+			start: map[string]interface{}{
+				"FieldA": "Banana",
+				"FieldB": 7,
+			},
+			end: map[string]interface{}{
+				"FieldA": "sushi # 1",
+				"FieldB": 7,
+			},
+		},
+		{
+			name:  "parse quoted value",
+			input: `FieldA = "  sushi # \"1\"\n" # Banananana`,
+			start: map[string]interface{}{
+				"FieldA": "Banana",
+				"FieldB": 7,
+			},
+			end: map[string]interface{}{
+				"FieldA": "  sushi # \"1\"\n",
+				"FieldB": 7,
+			},
+		},
 	}
 
 	for _, item := range suite {
@@ -177,7 +204,9 @@ func TestParseValid(t *testing.T) {
 			reader := strings.NewReader(item.input)
 
 			// In order to test default FlagSet
+			commandLine := flag.CommandLine
 			flag.CommandLine = flagSet
+			defer func() { flag.CommandLine = commandLine }()
 
 			if err := Parse(reader, nil); err != nil {
 				t.Error("unexpected error:", err)
@@ -229,6 +258,31 @@ func TestParseInvalid(t *testing.T) {
 				"FieldB": 7,
 			},
 		},
+		{
+			name:  "parse misspelled key",
+			input: "FieldC=10",
+			err:   "unknown key 'FieldC' on line 1, did you mean 'FieldA' or 'FieldB'?",
+			start: map[string]interface{}{
+				"FieldA": "Banana",
+				"FieldB": 7,
+			},
+		},
+		{
+			name:  "parse unterminated quote",
+			input: `FieldA = "sushi`,
+			err:   "unterminated quoted value on line 1",
+			start: map[string]interface{}{
+				"FieldA": "Banana",
+			},
+		},
+		{
+			name:  "parse text after quote",
+			input: `FieldA = "sushi" roll`,
+			err:   "unexpected text after quoted value on line 1",
+			start: map[string]interface{}{
+				"FieldA": "Banana",
+			},
+		},
 	}
 
 	for _, item := range suite {
@@ -246,10 +300,300 @@ func TestParseInvalid(t *testing.T) {
 	}
 }
 
+func TestParseUnknown(t *testing.T) {
+	input := `FieldA=sushi
+	          FeildA=1
+	          FieldB=9`
+
+	t.Run("ignore", func(t *testing.T) {
+		flagSet := makeFlagSet("ignore", map[string]interface{}{"FieldA": "Banana", "FieldB": 7})
+
+		if err := Parse(strings.NewReader(input), flagSet, IgnoreUnknown()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if v := flagSet.Lookup("FieldB").Value.String(); v != "9" {
+			t.Error("parsing did not continue past unknown key, got", v)
+		}
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		flagSet := makeFlagSet("callback", map[string]interface{}{"FieldA": "Banana", "FieldB": 7})
+
+		var warnings []string
+		err := Parse(strings.NewReader(input), flagSet, OnUnknown(func(err *UnknownKeyError) {
+			warnings = append(warnings, err.Error())
+		}))
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		expected := "unknown key 'FeildA' on line 2, did you mean 'FieldA'?"
+		if len(warnings) != 1 || warnings[0] != expected {
+			t.Errorf("warnings not equal\nexpected: [%s]\n  actual: %v", expected, warnings)
+		}
+	})
+
+	t.Run("collect", func(t *testing.T) {
+		flagSet := makeFlagSet("collect", map[string]interface{}{"FieldA": "Banana", "FieldB": 7})
+
+		var unknown []*UnknownKeyError
+		if err := Parse(strings.NewReader(input+"\nOther=2"), flagSet, CollectUnknown(&unknown)); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if len(unknown) != 2 {
+			t.Fatal("expected 2 unknown keys, got", len(unknown))
+		}
+		if unknown[0].Key != "FeildA" || unknown[0].Line != 2 {
+			t.Errorf("unexpected first key %s on line %d", unknown[0].Key, unknown[0].Line)
+		}
+		if unknown[1].Key != "Other" || unknown[1].Line != 4 || unknown[1].Suggestions != nil {
+			t.Errorf("unexpected second key %s on line %d", unknown[1].Key, unknown[1].Line)
+		}
+	})
+}
+
+func TestParseOperators(t *testing.T) {
+	flagSet := flag.NewFlagSet("operators", flag.ContinueOnError)
+	files := &stringSlice{"default.log"}
+	flagSet.Var(files, "Files", "")
+	count := flagSet.Int("Count", 7, "")
+	name := flagSet.String("Name", "Banana", "")
+
+	input := `Files += a.log
+	          Files = b.log
+	          Files -= default.log # not needed
+	          Count = 9
+	          Name = sushi
+	          !Count`
+
+	if err := Parse(strings.NewReader(input), flagSet); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if files.String() != "[a.log, b.log]" {
+		t.Error("unexpected files:", files.String())
+	}
+	if *count != 7 {
+		t.Error("count was not reset:", *count)
+	}
+	if *name != "sushi" {
+		t.Error("unexpected name:", *name)
+	}
+}
+
+func TestParseReplace(t *testing.T) {
+	suite := []struct {
+		name     string
+		input    string
+		opts     []Option
+		expected string
+	}{
+		{"replace", "Files = a.log\nFiles = b.log", nil, "[a.log, b.log]"},
+		{"append", "Files += a.log\nFiles = b.log", nil, "[default.log, a.log, b.log]"},
+		{"profile", "Files = a.log\n[profile.prod]\nFiles = p.log", []Option{Profile("prod")}, "[p.log]"},
+		{"profile append", "Files = a.log\n[profile.prod]\nFiles += p.log", []Option{Profile("prod")}, "[a.log, p.log]"},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet("replace", flag.ContinueOnError)
+			files := &stringSlice{"default.log"}
+			flagSet.Var(files, "Files", "")
+
+			if err := Parse(strings.NewReader(item.input), flagSet, item.opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if files.String() != item.expected {
+				t.Error("unexpected files:", files.String())
+			}
+		})
+	}
+}
+
+func TestParseOperatorsInvalid(t *testing.T) {
+	suite := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"append to scalar", "Count += 1", "cannot append to key 'Count' on line 1"},
+		{"remove from scalar", "Count -= 1", "cannot remove from key 'Count' on line 1"},
+		{"empty key", " = 1", "line 1 has no key"},
+		{"unknown reset", "!Cuont", "unknown key 'Cuont' on line 1"},
+		{"suggested reset", "!Cout", "unknown key 'Cout' on line 1, did you mean 'Count'?"},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet(item.name, flag.ContinueOnError)
+			flagSet.Int("Count", 7, "")
+
+			err := Parse(strings.NewReader(item.input), flagSet)
+			if err == nil {
+				t.Error("expected error:", item.err)
+			} else if item.err != err.Error() {
+				t.Errorf("errors don't match\nexpected: %s\n  actual: %s", item.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseBareBool(t *testing.T) {
+	flagSet := makeFlagSet("bare bool", map[string]interface{}{
+		"Verbose": false,
+		"Debug":   true,
+		"Trace":   true,
+		"Color":   true,
+		"FieldA":  "Banana",
+	})
+
+	input := `Verbose # on
+	          no-Debug
+	          !Trace
+	          no-Color`
+
+	if err := Parse(strings.NewReader(input), flagSet); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := map[string]string{
+		"Verbose": "true",
+		"Debug":   "false",
+		"Trace":   "false",
+		"Color":   "false",
+	}
+	for k, v := range expected {
+		if actual := flagSet.Lookup(k).Value.String(); actual != v {
+			t.Errorf("values for %s not equal\nexpected: %s\n  actual: %s", k, v, actual)
+		}
+	}
+
+	err := Parse(strings.NewReader("\nFieldA"), flagSet)
+	if err == nil || err.Error() != "key 'FieldA' on line 2 has no value" {
+		t.Error("unexpected error:", err)
+	}
+
+	err = Parse(strings.NewReader("Verbos"), flagSet)
+	if err == nil || err.Error() != "line 1 has no key, did you mean 'Verbose'?" {
+		t.Error("unexpected error:", err)
+	}
+
+	err = Parse(strings.NewReader("NewFeature\nno-NewFeature"), flagSet, IgnoreUnknown())
+	if err != nil {
+		t.Error("unexpected error ignoring unknown keys:", err)
+	}
+
+	var unknown []*UnknownKeyError
+	err = Parse(strings.NewReader("Verbose\nno-Verbos\n!Tracer"), flagSet, CollectUnknown(&unknown))
+	if err != nil {
+		t.Error("unexpected error collecting unknown keys:", err)
+	}
+	if len(unknown) != 2 || unknown[0].Key != "no-Verbos" || !unknown[0].Bare ||
+		unknown[1].Key != "Tracer" || unknown[1].Bare {
+		t.Errorf("unexpected unknown keys %+v", unknown)
+	}
+}
+
+// registry is a minimal FlagSet that is not a flag.FlagSet.
+type registry map[string]*flag.Flag
+
+func (r registry) Var(value flag.Value, name string, usage string) {
+	r[name] = &flag.Flag{Name: name, Usage: usage, Value: value, DefValue: value.String()}
+}
+
+func (r registry) Lookup(name string) *flag.Flag { return r[name] }
+
+func (r registry) VisitAll(fn func(*flag.Flag)) {
+	for _, f := range r {
+		fn(f)
+	}
+}
+
+func TestParseRegistry(t *testing.T) {
+	reg := registry{}
+	files := &stringSlice{}
+	reg.Var(files, "Files", "")
+
+	if err := Parse(strings.NewReader("Files = a\nFiles += b"), reg); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if files.String() != "[a, b]" {
+		t.Error("unexpected files:", files.String())
+	}
+
+	err := Parse(strings.NewReader("File = c"), reg)
+	if err == nil || err.Error() != "unknown key 'File' on line 1, did you mean 'Files'?" {
+		t.Error("unexpected error:", err)
+	}
+}
+
 type badreader struct{}
 
 func (badreader) Read(p []byte) (int, error) { return 0, errors.New("test") }
 
+const testProfiles = `FieldA = base
+[profile.prod] # production
+FieldB = 2
+FieldA = prod
+[profile.dev]
+FieldA = dev
+FieldC = 1
+`
+
+func TestParseProfile(t *testing.T) {
+	suite := []struct {
+		name   string
+		opts   []Option
+		fieldA string
+		fieldB int
+	}{
+		{"no profile", nil, "base", 0},
+		{"profile", []Option{Profile("prod")}, "prod", 2},
+		{"missing profile", []Option{Profile("staging")}, "base", 0},
+		{"only profile", []Option{OnlyProfile("prod")}, "prod", 2},
+		{"only dev profile", []Option{OnlyProfile("dev"), IgnoreUnknown()}, "dev", 0},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet("TestParseProfile", flag.ContinueOnError)
+			fieldA := flagSet.String("FieldA", "", "")
+			fieldB := flagSet.Int("FieldB", 0, "")
+
+			if err := Parse(strings.NewReader(testProfiles), flagSet, item.opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if *fieldA != item.fieldA || *fieldB != item.fieldB {
+				t.Errorf("unexpected values %q %d", *fieldA, *fieldB)
+			}
+		})
+	}
+}
+
+func TestParseProfileInvalid(t *testing.T) {
+	suite := []string{"[prod]", "[profile.]", "[profile.prod", "[profile.a b]"}
+
+	for _, input := range suite {
+		flagSet := flag.NewFlagSet("TestParseProfileInvalid", flag.ContinueOnError)
+		err := Parse(strings.NewReader("\n"+input), flagSet)
+		if err == nil {
+			t.Error("expected parse error for", input)
+		} else if err.Error() != "line 2 has an invalid section" {
+			t.Error("unexpected error:", err.Error())
+		}
+	}
+
+	flagSet := flag.NewFlagSet("TestParseProfileInvalid", flag.ContinueOnError)
+	flagSet.String("FieldA", "", "")
+	err := Parse(strings.NewReader(testProfiles), flagSet, Profile("dev"))
+	if err == nil {
+		t.Error("expected parse error")
+	} else if err.Error() != "unknown key 'FieldC' on line 7, did you mean 'FieldA'?" {
+		t.Error("unexpected error:", err.Error())
+	}
+}
+
 func TestParseBadReader(t *testing.T) {
 	flagSet := flag.NewFlagSet("", flag.ContinueOnError)
 
@@ -316,11 +660,36 @@ func TestParseFile(t *testing.T) {
 	}
 }
 
+func TestParseTypedNil(t *testing.T) {
+	flagSet := flag.NewFlagSet("TestParseTypedNil", flag.ContinueOnError)
+	foo := flagSet.Int("Foo", 5, "")
+
+	// A nil *flag.FlagSet selects the default FlagSet, as nil does
+	commandLine := flag.CommandLine
+	flag.CommandLine = flagSet
+	defer func() { flag.CommandLine = commandLine }()
+
+	var typedNil *flag.FlagSet
+	if err := Parse(strings.NewReader("Foo=10"), typedNil); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if *foo != 10 {
+		t.Error("failed to set value with Parse")
+	}
+
+	err := ParseFile("/goflagbuilder-bad-test", typedNil)
+	if err == nil || err.Error() != "open /goflagbuilder-bad-test: no such file or directory" {
+		t.Error("unexpected error:", err)
+	}
+}
+
 func TestParseFileBad(t *testing.T) {
 	flagSet := flag.NewFlagSet("TestParseFileBad", flag.ContinueOnError)
 
 	// In order to test default FlagSet
+	commandLine := flag.CommandLine
 	flag.CommandLine = flagSet
+	defer func() { flag.CommandLine = commandLine }()
 
 	err := ParseFile("/goflagbuilder-bad-test", nil)
 
@@ -0,0 +1,103 @@
+package conf
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+)
+
+// WriteOption configures optional behavior of Write.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	defaults   bool
+	nonDefault bool
+}
+
+// DefaultValues writes the default value of each flag rather than its
+// current value, producing a template configuration file.
+func DefaultValues() WriteOption {
+	return func(o *writeOptions) { o.defaults = true }
+}
+
+// NonDefault writes only the flags whose current value differs from their
+// default.
+func NonDefault() WriteOption {
+	return func(o *writeOptions) { o.nonDefault = true }
+}
+
+// Write emits every flag in the given flagset as a key/value pair that Parse
+// will read back, preceded by its usage string as a comment.  Slice values
+// are written as one line per element.  Keys are written in lexical order.  If
+// flagSet is nil, the global flag.CommandLine FlagSet is used.
+func Write(out io.Writer, flagSet FlagSet, opts ...WriteOption) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	var o writeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	w := bufio.NewWriter(out)
+	first := true
+
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if o.nonDefault && f.Value.String() == f.DefValue {
+			return
+		}
+
+		if !first {
+			w.WriteString("\n")
+		}
+		first = false
+
+		if f.Usage != "" {
+			for _, line := range strings.Split(f.Usage, "\n") {
+				w.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+			}
+		}
+
+		items, isSlice := flagutil.SliceItems(f.Value)
+		switch {
+		case o.defaults && isSlice:
+			// The default of a slice is only available in its
+			// printed form, which cannot be parsed back.
+			fmt.Fprintf(w, "# %s = %s\n", f.Name, f.DefValue)
+
+		case o.defaults:
+			fmt.Fprintf(w, "%s = %s\n", f.Name, quote(f.DefValue))
+
+		case isSlice:
+			for _, item := range items {
+				fmt.Fprintf(w, "%s = %s\n", f.Name, quote(item))
+			}
+
+		default:
+			fmt.Fprintf(w, "%s = %s\n", f.Name, quote(f.Value.String()))
+		}
+	})
+
+	return w.Flush()
+}
+
+// quote returns s in a form that Parse reads back unchanged, quoting it only
+// if necessary.
+func quote(s string) string {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, `#"\`) {
+		return strconv.Quote(s)
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
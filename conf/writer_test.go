@@ -0,0 +1,134 @@
+package conf
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+type stringSlice []string
+
+func (s *stringSlice) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func (s *stringSlice) Remove(v string) error {
+	var kept stringSlice
+	for _, item := range *s {
+		if item != v {
+			kept = append(kept, item)
+		}
+	}
+	*s = kept
+	return nil
+}
+
+func (s *stringSlice) Clear() error {
+	*s = nil
+	return nil
+}
+
+func (s *stringSlice) Get() interface{} { return []string(*s) }
+func (s *stringSlice) String() string   { return "[" + strings.Join(*s, ", ") + "]" }
+
+func makeWriteFlagSet() *flag.FlagSet {
+	flagSet := flag.NewFlagSet("write", flag.ContinueOnError)
+	flagSet.String("Name", "Banana", "Name of the fruit")
+	flagSet.Int("Count", 7, "")
+	flagSet.String("Quoted", "", "Needs quoting\nover two lines")
+	flagSet.Var(&stringSlice{}, "Files", "")
+	return flagSet
+}
+
+func TestWrite(t *testing.T) {
+	suite := []struct {
+		name   string
+		args   []string
+		opts   []WriteOption
+		output string
+	}{
+		{
+			name: "current values",
+			args: []string{"-Count", "9", "-Quoted", " a # b ", "-Files", "x", "-Files", `y"z`},
+			output: `Count = 9
+
+Files = x
+Files = "y\"z"
+
+# Name of the fruit
+Name = Banana
+
+# Needs quoting
+# over two lines
+Quoted = " a # b "
+`,
+		},
+		{
+			name: "default values",
+			args: []string{"-Count", "9", "-Files", "x"},
+			opts: []WriteOption{DefaultValues()},
+			output: `Count = 7
+
+# Files = []
+
+# Name of the fruit
+Name = Banana
+
+# Needs quoting
+# over two lines
+Quoted = ""
+`,
+		},
+		{
+			name:   "non-default values",
+			args:   []string{"-Count", "9"},
+			opts:   []WriteOption{NonDefault()},
+			output: "Count = 9\n",
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			flagSet := makeWriteFlagSet()
+			if err := flagSet.Parse(item.args); err != nil {
+				t.Fatal("error parsing args:", err)
+			}
+
+			var buf bytes.Buffer
+			if err := Write(&buf, flagSet, item.opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+
+			if buf.String() != item.output {
+				t.Errorf("output not equal\nexpected: %q\n  actual: %q", item.output, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	flagSet := makeWriteFlagSet()
+	args := []string{"-Name", `"quoted" \# name`, "-Quoted", "\ttab\n", "-Files", "a#b", "-Files", " c "}
+	if err := flagSet.Parse(args); err != nil {
+		t.Fatal("error parsing args:", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, flagSet); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	readSet := makeWriteFlagSet()
+	if err := Parse(&buf, readSet); err != nil {
+		t.Fatal("error parsing output:", err)
+	}
+
+	flagSet.VisitAll(func(f *flag.Flag) {
+		actual := readSet.Lookup(f.Name).Value.String()
+		if actual != f.Value.String() {
+			t.Errorf("values for %s not equal\nexpected: %q\n  actual: %q", f.Name, f.Value.String(), actual)
+		}
+	})
+}
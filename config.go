@@ -0,0 +1,128 @@
+package goflagbuilder
+
+import (
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFlag is the name of the flag registered by Config.
+const ConfigFlag = "config"
+
+// Config returns a Source that reads each file named by a repeatable -config
+// flag, which NewLoader defines on the loader's flag set, in order and with
+// the given options.  If the flag is not given, the Source instead reads every
+// configuration file found in the directories of SearchPaths, using the base
+// of the flag set's name as the application name as env.Parse does.  Within
+// each directory it looks for the application name with the extension of any
+// known format, such as "myapp.toml".
+//
+// A file named by the flag must exist.  Files that are not found in the search
+// paths are skipped, but any other error reading one is returned, so that an
+// unreadable file is not silently ignored.  Loader.Files reports the files that
+// were read.
+func Config(opts ...ParseOption) Source {
+	return configSource{opts}
+}
+
+type configSource struct {
+	opts []ParseOption
+}
+
+func (c configSource) defineFlags(flagSet *flag.FlagSet) {
+	if flagSet.Lookup(ConfigFlag) == nil {
+		flagSet.Var(&fileList{}, ConfigFlag, "Configuration file to read; may be repeated")
+	}
+}
+
+func (c configSource) Apply(l *Loader) error {
+	// The loader sets the flag again after all sources are applied.
+	if files, ok := l.flagSet.Lookup(ConfigFlag).Value.(*fileList); ok {
+		*files = nil
+	}
+
+	filenames := l.CommandLine(ConfigFlag)
+	if len(filenames) > 0 {
+		for _, filename := range filenames {
+			if err := l.ParseFile(filename, c.opts...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	app := path.Base(l.flagSet.Name())
+	for _, dir := range SearchPaths(app) {
+		for _, ext := range extensions() {
+			err := l.ParseFile(filepath.Join(dir, app+ext), c.opts...)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SearchPaths returns the directories searched for the configuration of the
+// named application, from lowest to highest precedence: /etc/<app>, each
+// directory of $XDG_CONFIG_DIRS/<app> in reverse order, $XDG_CONFIG_HOME/<app>,
+// and the working directory.  The XDG variables take their defaults from the
+// XDG Base Directory Specification when unset.
+func SearchPaths(app string) []string {
+	paths := []string{filepath.Join("/etc", app)}
+
+	dirs := os.Getenv("XDG_CONFIG_DIRS")
+	if dirs == "" {
+		dirs = "/etc/xdg"
+	}
+	list := filepath.SplitList(dirs)
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i] != "" {
+			paths = append(paths, filepath.Join(list[i], app))
+		}
+	}
+
+	home := os.Getenv("XDG_CONFIG_HOME")
+	if home == "" {
+		if dir, err := os.UserHomeDir(); err == nil {
+			home = filepath.Join(dir, ".config")
+		}
+	}
+	if home != "" {
+		paths = append(paths, filepath.Join(home, app))
+	}
+
+	return append(paths, ".")
+}
+
+// extensions returns the file extensions of the known formats, in order of
+// precedence.
+func extensions() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	var exts []string
+	for i := len(formats) - 1; i >= 0; i-- {
+		exts = append(exts, formats[i].Extensions...)
+	}
+	return exts
+}
+
+// fileList is a flag value accumulating each file name given.
+type fileList []string
+
+func (f *fileList) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func (f *fileList) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Get() interface{} { return []string(*f) }
@@ -0,0 +1,102 @@
+package goflagbuilder
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchPaths(t *testing.T) {
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	defer os.Setenv("XDG_CONFIG_DIRS", os.Getenv("XDG_CONFIG_DIRS"))
+
+	os.Setenv("XDG_CONFIG_HOME", "/home/user/.config")
+	os.Setenv("XDG_CONFIG_DIRS", "/etc/xdg:/usr/etc/xdg")
+
+	assert.Equal(t, []string{
+		"/etc/myapp",
+		"/usr/etc/xdg/myapp",
+		"/etc/xdg/myapp",
+		"/home/user/.config/myapp",
+		".",
+	}, SearchPaths("myapp"))
+}
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	defer os.Setenv("XDG_CONFIG_DIRS", os.Getenv("XDG_CONFIG_DIRS"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "home"))
+	os.Setenv("XDG_CONFIG_DIRS", filepath.Join(dir, "system"))
+
+	system := filepath.Join(dir, "system", "configtest", "configtest.conf")
+	home := filepath.Join(dir, "home", "configtest", "configtest.json")
+	explicit := filepath.Join(dir, "explicit.conf")
+	require.NoError(t, os.MkdirAll(filepath.Dir(system), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Dir(home), 0755))
+	require.NoError(t, ioutil.WriteFile(system, []byte("Domain = system.example\nPort = 1"), 0644))
+	require.NoError(t, ioutil.WriteFile(home, []byte(`{"Port": 2}`), 0644))
+	require.NoError(t, ioutil.WriteFile(explicit, []byte("Label = explicit"), 0644))
+
+	suite := []struct {
+		name   string
+		args   []string
+		files  []string
+		origin string
+		port   int
+		label  string
+	}{
+		{"search", nil, []string{system, home}, home, 2, ""},
+		{"explicit", []string{"-config", explicit, "-config", system}, []string{explicit, system}, system, 1, "explicit"},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			conf := &loadercomponent{}
+			flagSet := flag.NewFlagSet("configtest", flag.ContinueOnError)
+			require.NoError(t, Into(flagSet, conf))
+
+			loader := NewLoader(flagSet, Config())
+			require.NoError(t, loader.Load(item.args))
+			require.NoError(t, loader.Load(item.args))
+
+			assert.Equal(t, item.files, loader.Files())
+			assert.Equal(t, item.origin, loader.Origin("Port"))
+			assert.Equal(t, item.port, conf.Port)
+			assert.Equal(t, item.label, conf.Label)
+			assert.Equal(t, "system.example", conf.Domain)
+			assert.Equal(t, item.args == nil, flagSet.Lookup(ConfigFlag).Value.String() == "")
+			assert.Nil(t, flag.CommandLine.Lookup(ConfigFlag))
+		})
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", dir)
+
+	flagSet := flag.NewFlagSet("configtest", flag.ContinueOnError)
+	require.NoError(t, Into(flagSet, &loadercomponent{}))
+	loader := NewLoader(flagSet, Config())
+
+	missing := filepath.Join(dir, "missing.conf")
+	err = loader.Load([]string{"-config", missing})
+	assert.True(t, os.IsNotExist(err), "unexpected error: %v", err)
+
+	unreadable := filepath.Join(dir, "configtest", "configtest.conf")
+	require.NoError(t, os.MkdirAll(unreadable, 0755))
+	err = loader.Load(nil)
+	assert.EqualError(t, err, "read "+unreadable+": is a directory")
+}
@@ -13,14 +13,36 @@ struct fields to a primitive.  Nested maps and structs are followed,
 producing dot-notation hierarchical keys such as Obj.Field.
 
 Primitive types understood by goflagbuilder include bool, float64,
-int64, int, string, uint64, and uint.
+int64, int, string, uint64, and uint.  A time.Time is read in RFC 3339
+form.
 
 Primitive fields in the given object and sub-objects must be settable.
 In general this means structs should be passed in as pointers.  Maps
 may also be set directly.
 
 Struct fields may have a "help" struct tag, which will set the usage
-string for the corresponding flag.
+string for the corresponding flag.  An "env" struct tag lists the environment
+variables that the env package reads for the field, such as `env:"PORT,HTTP_PORT"`,
+in place of the name it would otherwise derive.
+
+Besides flag.Getter, the constructed flag values provide Remove, which
+removes matching elements from a slice, Clear, which removes them all, and
+Reset, which restores the value held when the flags were built.  The conf
+package uses these for its "-=" and "!Key" operators, and the parsers clear a
+slice before a file assigns it, so that the file replaces its default.
+
+ParseFile reads a configuration file in any of the supported formats, and
+its IgnoreUnknown and OnUnknown options allow keys that match no flag.  A
+Loader layers files, the environment, and the command line over the defaults
+in that order of precedence, and reports which layer set each key.  Its
+Config source adds a -config flag and otherwise searches the standard
+locations for the application's configuration, and its Profile source
+selects a profile whose overrides are applied over each file.  ExpandArgs
+reads "@path" arguments from files before the command line is parsed.
+
+A Watcher keeps a configuration current in a long running program, loading a
+fresh copy whenever its files change and swapping it in once it validates.
+Its ReloadOnHangup method does the same whenever the process receives SIGHUP.
 
 */
 package goflagbuilder
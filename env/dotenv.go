@@ -0,0 +1,232 @@
+package env
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+)
+
+// ParseReader reads variables from a .env file in the given Reader and parses
+// them into matching flags in the given flagset, exactly as Parse does with
+// the environment.  Variables in the file are not added to the environment.
+// If flagSet is nil, the global flag.CommandLine FlagSet is used.
+//
+// Each line of the file is of the form NAME=VALUE, optionally preceded by
+// "export".  Lines starting with "#" are comments.  A value may be enclosed in
+// single quotes, which are taken literally, or in double quotes, which
+// recognize the escapes \n, \r, \t, \", \$, and \\.  Quoted values may span
+// several lines.  Unquoted values are trimmed, and end at a "#" preceded by
+// whitespace.  As in the shell, a single-quoted value may hold a quote by
+// closing the quotes around an escaped one:
+//
+//	export NAME='it'\''s'
+func ParseReader(in io.Reader, flagSet FlagSet, opts ...Option) error {
+	vars, err := readDotenv(in)
+	if err != nil {
+		return err
+	}
+
+	return parse(flagSet, buildOptions(append(opts, variableMap(vars))))
+}
+
+// ParseFile reads variables from the .env file indicated by filename and
+// parses them into matching flags in the given flagset.  It is identical to
+// calling ParseReader on a File opened from filename.  If flagSet is nil, the
+// global flag.CommandLine FlagSet is used.
+func ParseFile(filename string, flagSet FlagSet, opts ...Option) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return ParseReader(in, flagSet, opts...)
+}
+
+// readDotenv returns the variables defined in a .env file.
+func readDotenv(in io.Reader) (map[string]string, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	src := string(data)
+	line := 1
+
+	for len(src) > 0 {
+		var str string
+		if i := strings.IndexByte(src, '\n'); i >= 0 {
+			str, src = src[:i], src[i+1:]
+		} else {
+			str, src = src, ""
+		}
+		start := line
+		line++
+
+		str = strings.TrimSpace(str)
+		if str == "" || str[0] == '#' {
+			continue
+		}
+
+		if strings.HasPrefix(str, "export ") || strings.HasPrefix(str, "export\t") {
+			str = strings.TrimSpace(str[len("export"):])
+		}
+
+		index := strings.Index(str, "=")
+		if index < 0 {
+			return nil, fmt.Errorf("line %d has no '='", start)
+		}
+
+		name := strings.TrimSpace(str[:index])
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("line %d has an invalid name", start)
+		}
+
+		value := strings.TrimLeft(str[index+1:], " \t")
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = value[:i]
+			}
+			if i := strings.Index(value, "\t#"); i >= 0 {
+				value = value[:i]
+			}
+			vars[name] = strings.TrimSpace(value)
+			continue
+		}
+
+		// A quoted value may continue onto the following lines.  Within
+		// single quotes, the shell idiom '\'' stands for a single quote.
+		quote := value[0]
+		value = value[1:]
+		from := 0
+		for {
+			end := closingQuote(value, from, quote)
+			if end >= 0 && quote == '\'' && strings.HasPrefix(value[end+1:], `\''`) {
+				value = value[:end] + "'" + value[end+4:]
+				from = end + 1
+				continue
+			}
+			if end >= 0 {
+				rest := strings.TrimSpace(value[end+1:])
+				if rest != "" && rest[0] != '#' {
+					return nil, fmt.Errorf("unexpected text after quoted value on line %d", line-1)
+				}
+				value = value[:end]
+				break
+			}
+
+			if src == "" {
+				return nil, fmt.Errorf("unterminated quoted value on line %d", start)
+			}
+
+			next := src
+			if i := strings.IndexByte(src, '\n'); i >= 0 {
+				next, src = src[:i], src[i+1:]
+			} else {
+				src = ""
+			}
+			value += "\n" + next
+			line++
+		}
+
+		if quote == '"' {
+			value = unescape(value)
+		}
+		vars[name] = value
+	}
+
+	return vars, nil
+}
+
+// closingQuote returns the index of the quote ending s at or after from, or -1
+// if there is none.  Within double quotes, a backslash escapes the following
+// character.
+func closingQuote(s string, from int, quote byte) int {
+	for i := from; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+		} else if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+var unescaper = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\$`, "$", `\\`, `\`)
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
+
+var escaper = strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`, `"`, `\"`, "$", `\$`, `\`, `\\`)
+
+// Export makes Write emit a shell script of export commands rather than a
+// .env file.  It has no effect on parsing.
+func Export() Option {
+	return func(o *options) { o.export = true }
+}
+
+// Write emits every flag in the given flagset as a variable assignment that
+// ParseReader will read back, preceded by its usage string as a comment.
+// Variables are named as Parse expects them given the same options, using the
+// first name of a Variabler.  If flagSet is nil, the global flag.CommandLine
+// FlagSet is used.
+//
+// A variable sets a single element of a slice, so slices cannot be read back
+// and are noted in a comment instead.
+func Write(out io.Writer, flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	o := buildOptions(opts)
+
+	w := bufio.NewWriter(out)
+	name := o.prefixOf(flagSet)
+	first := true
+
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if !first {
+			w.WriteString("\n")
+		}
+		first = false
+
+		if f.Usage != "" {
+			for _, line := range strings.Split(f.Usage, "\n") {
+				w.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+			}
+		}
+
+		value := f.Value.String()
+		variable := o.variables(name, f)[0]
+		_, isSlice := flagutil.SliceItems(f.Value)
+		switch {
+		case isSlice:
+			fmt.Fprintf(w, "# %s is a list, which a variable cannot hold\n", variable)
+
+		case o.export:
+			fmt.Fprintf(w, "export %s='%s'\n", variable, strings.Replace(value, "'", `'\''`, -1))
+
+		default:
+			fmt.Fprintf(w, "%s=%s\n", variable, quote(value))
+		}
+	})
+
+	return w.Flush()
+}
+
+// quote returns s in a form that ParseReader reads back unchanged, quoting it
+// only if necessary.
+func quote(s string) string {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, "#\"'$\\\n\r\t ") {
+		return `"` + escaper.Replace(s) + `"`
+	}
+	return s
+}
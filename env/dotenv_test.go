@@ -0,0 +1,193 @@
+package env
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseReader(t *testing.T) {
+	input := `# Settings for local development
+DOTENV_FIELDA=Sushi
+export DOTENV_FIELDB = 20 # trailing comment
+DOTENV_FIELDC='single # "quoted" \n'
+DOTENV_FIELDD="double \"quoted\"\tescaped \$HOME
+second line" # comment
+DOTENV_FIELDE=
+export DOTENV_FIELDF='it'\''s '\'''
+OTHER=ignored
+`
+
+	flagSet := makeFlagSet("dotenv", map[string]interface{}{
+		"FieldA": "Banana",
+		"FieldB": 7,
+		"FieldC": "",
+		"FieldD": "",
+		"FieldE": "default",
+		"FieldF": "",
+	})
+
+	if err := ParseReader(strings.NewReader(input), flagSet); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := map[string]string{
+		"FieldA": "Sushi",
+		"FieldB": "20",
+		"FieldC": `single # "quoted" \n`,
+		"FieldD": "double \"quoted\"\tescaped $HOME\nsecond line",
+		"FieldE": "",
+		"FieldF": "it's '",
+	}
+	for k, v := range expected {
+		if actual := flagSet.Lookup(k).Value.String(); v != actual {
+			t.Errorf("values for %s not equal\nexpected: %q\n  actual: %q", k, v, actual)
+		}
+	}
+}
+
+func TestParseReaderInvalid(t *testing.T) {
+	suite := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"no equals", "A=1\nFIELDA", "line 2 has no '='"},
+		{"invalid name", "MY FIELD=1", "line 1 has an invalid name"},
+		{"unterminated quote", "A=1\nB=\"two\nthree", "unterminated quoted value on line 2"},
+		{"text after quote", "A='one' two", "unexpected text after quoted value on line 1"},
+		{"text after escaped quote", `A='it'\''s' two`, "unexpected text after quoted value on line 1"},
+		{"text after multiline quote", "A=\"one\ntwo\" three", "unexpected text after quoted value on line 2"},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			flagSet := makeFlagSet(item.name, map[string]interface{}{"FieldA": "Banana"})
+
+			err := ParseReader(strings.NewReader(item.input), flagSet)
+			if err == nil {
+				t.Error("expected error:", item.err)
+			} else if item.err != err.Error() {
+				t.Errorf("errors don't match\nexpected: %s\n  actual: %s", item.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "goflagbuilder-test")
+	if err != nil {
+		t.Fatal("failed to create temp file:", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString("TESTPARSEFILE_FOO=10\n"); err != nil {
+		t.Fatal("failed to write temp file:", err)
+	}
+	tmpFile.Close()
+
+	flagSet := flag.NewFlagSet("TestParseFile", flag.ContinueOnError)
+	foo := flagSet.Int("Foo", 5, "")
+
+	if err := ParseFile(tmpFile.Name(), flagSet); err != nil {
+		t.Error("failed to parse file:", err)
+	}
+	if *foo != 10 {
+		t.Error("failed to set value")
+	}
+
+	if err := ParseFile("/goflagbuilder-bad-test", flagSet); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	flagSet := flag.NewFlagSet("my app", flag.ContinueOnError)
+	flagSet.String("Name", "Banana", "Name of the fruit")
+	flagSet.Int("Database.Port", 5432, "")
+	flagSet.String("Quoted", `it's "$5" # cheap`, "")
+	flagSet.String("Empty", "", "")
+
+	suite := []struct {
+		name   string
+		opts   []Option
+		output string
+	}{
+		{
+			name: "dotenv",
+			output: `MY_APP_DATABASE_PORT=5432
+
+MY_APP_EMPTY=""
+
+# Name of the fruit
+MY_APP_NAME=Banana
+
+MY_APP_QUOTED="it's \"\$5\" # cheap"
+`,
+		},
+		{
+			name: "export",
+			opts: []Option{Export()},
+			output: `export MY_APP_DATABASE_PORT='5432'
+
+export MY_APP_EMPTY=''
+
+# Name of the fruit
+export MY_APP_NAME='Banana'
+
+export MY_APP_QUOTED='it'\''s "$5" # cheap'
+`,
+		},
+		{
+			name: "prefix",
+			opts: []Option{Prefix("APP"), Naming(strings.ToLower)},
+			output: `APP_database.port=5432
+
+APP_empty=""
+
+# Name of the fruit
+APP_name=Banana
+
+APP_quoted="it's \"\$5\" # cheap"
+`,
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, flagSet, item.opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if buf.String() != item.output {
+				t.Errorf("output not equal\nexpected: %s\n  actual: %s", item.output, buf.String())
+			}
+		})
+	}
+
+	for _, item := range suite[:2] {
+		t.Run(item.name+" round trip", func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, flagSet, item.opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+
+			readSet := flag.NewFlagSet("my app", flag.ContinueOnError)
+			readSet.String("Name", "", "")
+			readSet.Int("Database.Port", 0, "")
+			readSet.String("Quoted", "", "")
+			readSet.String("Empty", "x", "")
+			if err := ParseReader(&buf, readSet); err != nil {
+				t.Fatal("error parsing output:", err)
+			}
+			flagSet.VisitAll(func(f *flag.Flag) {
+				if actual := readSet.Lookup(f.Name).Value.String(); actual != f.Value.String() {
+					t.Errorf("values for %s not equal\nexpected: %q\n  actual: %q", f.Name, f.Value.String(), actual)
+				}
+			})
+		})
+	}
+}
@@ -1,7 +1,7 @@
 /*
 
 Package env provides a means to read environment variables into values backed
-by a flag.FlagSet.
+by a flag.FlagSet, or any other FlagSet.
 
 Environment variables are of the format:
 
@@ -9,53 +9,389 @@ Environment variables are of the format:
 
 Where FSNAME is the name of the given flag.FlagSet. KEYNAME is the name of
 the flag. All spaces and periods are replaced with underscores in both
-strings. The name of the FlagSet is cleaned with path.Base().
+strings. The name of the FlagSet is cleaned with path.Base(). A FlagSet
+without a Name method has no prefix.  The Prefix and Naming options override
+this naming.
+
+By default, variables that match no flag are ignored.  With the Strict or
+OnUnknown options, any variable with the prefix that matches no flag is
+reported instead, with suggestions for similarly named variables.  Check
+reports flags whose variables share a name.
+
+A value that cannot be set is reported as an *Error naming its variable and
+key.  Parsing stops at the first such error unless the CollectErrors option is
+given, in which case every error is returned together.
+
+Variables are read from the environment of the process unless the Environ or
+LookupFunc option gives another source, such as the environment of a child
+process or a captured snapshot.
+
+Secrets are often provided as files rather than in variables.  With the
+SecretFiles option, a variable such as APP_DB_PASSWORD_FILE names a file
+holding the value of APP_DB_PASSWORD.  With the Credentials option, a value
+may also be read from a file named by its key in the directory given by
+$CREDENTIALS_DIRECTORY, as systemd provides.
+
+A flag value may instead name its own variables by implementing Variabler, as
+goflagbuilder.Into does for struct fields tagged with one or more names:
+
+	Port int `env:"PORT,HTTP_PORT"`
+
+The first of these variables that is set is used, and the derived name is not
+consulted.
+
+Variables may also be read from a .env file with ParseFile or ParseReader,
+and Write generates such a file, or a shell script of export commands, from
+the current values of a FlagSet.
 
 */
 package env
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/suggest"
 )
 
 var replacer = strings.NewReplacer(" ", "_", ".", "_")
 
+// Option configures optional behavior of Parse, ParseReader, ParseFile, and
+// Write.
+type Option func(*options)
+
+type options struct {
+	prefix  *string
+	naming  func(key string) string
+	export  bool
+	unknown func(*UnknownVariableError) error
+
+	secretFiles bool
+	credentials bool
+	collect     bool
+
+	// lookup finds variables, and names lists them if that is possible.
+	lookup func(string) (string, bool)
+	names  []string
+}
+
+// UnknownVariableError describes an environment variable with the prefix of a
+// FlagSet that does not match any flag.  Suggestions holds the names of
+// similarly spelled variables that do, if any.
+type UnknownVariableError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e *UnknownVariableError) Error() string {
+	msg := fmt.Sprintf("unknown variable '%s'", e.Name)
+	if s := suggest.Format(e.Suggestions); s != "" {
+		msg += ", " + s
+	}
+	return msg
+}
+
+// Prefix names variables with the given prefix, followed by an underscore,
+// rather than the name of the FlagSet.  If prefix is "", variables are named
+// by their keys alone.
+func Prefix(prefix string) Option {
+	return func(o *options) { o.prefix = &prefix }
+}
+
+// Naming formats keys into the part of variable names after the prefix with
+// fn, rather than by replacing spaces and periods with underscores and
+// converting to upper case.
+func Naming(fn func(key string) string) Option {
+	return func(o *options) { o.naming = fn }
+}
+
+// Environ reads variables from environ, a list of "NAME=VALUE" strings such as
+// os.Environ returns or exec.Cmd takes, rather than from the environment of the
+// process.  Later entries for a name take precedence, as in exec.Cmd.
+func Environ(environ []string) Option {
+	vars := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i >= 0 {
+			vars[kv[:i]] = kv[i+1:]
+		}
+	}
+	return variableMap(vars)
+}
+
+// LookupFunc reads variables with fn, which has the signature of os.LookupEnv,
+// rather than from the environment of the process.  As the variables cannot be
+// listed, Strict and OnUnknown have no effect.
+func LookupFunc(fn func(name string) (string, bool)) Option {
+	return func(o *options) {
+		o.lookup = fn
+		o.names = nil
+	}
+}
+
+// variableMap reads variables from vars.
+func variableMap(vars map[string]string) Option {
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+
+	return func(o *options) {
+		o.lookup = func(name string) (string, bool) {
+			value, ok := vars[name]
+			return value, ok
+		}
+		o.names = names
+	}
+}
+
+// CollectErrors continues parsing after a value cannot be set, returning an
+// Errors holding every failure rather than only the first.
+func CollectErrors() Option {
+	return func(o *options) { o.collect = true }
+}
+
+// Strict makes parsing fail if a variable with the prefix does not match any
+// flag, before any flag is set.  It has no effect when there is no prefix.
+func Strict() Option {
+	return func(o *options) {
+		o.unknown = func(err *UnknownVariableError) error { return err }
+	}
+}
+
+// OnUnknown calls fn for each variable with the prefix that does not match any
+// flag, and then continues parsing.  This may be used to log a warning.  It
+// has no effect when there is no prefix.
+func OnUnknown(fn func(err *UnknownVariableError)) Option {
+	return func(o *options) {
+		o.unknown = func(err *UnknownVariableError) error {
+			fn(err)
+			return nil
+		}
+	}
+}
+
+func buildOptions(opts []Option) options {
+	o := options{naming: format}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.lookup == nil {
+		o.lookup = os.LookupEnv
+		for _, kv := range os.Environ() {
+			o.names = append(o.names, strings.SplitN(kv, "=", 2)[0])
+		}
+	}
+
+	return o
+}
+
 // Parse reads environment variables and parses into matching flags in the given
 // flagset.  If flagSet is nil, the global flag.CommandLine FlagSet is used.
-func Parse(flagSet *flag.FlagSet) error {
-	if flagSet == nil {
+func Parse(flagSet FlagSet, opts ...Option) error {
+	return parse(flagSet, buildOptions(opts))
+}
+
+// parse sets each flag in flagSet whose variable is found.
+func parse(flagSet FlagSet, o options) error {
+	if flagutil.IsNil(flagSet) {
 		flagSet = flag.CommandLine
 	}
 
-	name := format(path.Base(flagSet.Name()))
-	if name != "" {
-		name += "_"
+	name := o.prefixOf(flagSet)
+
+	if err := o.checkUnknown(flagSet, name); err != nil {
+		return err
 	}
 
-	var err error
+	var errs Errors
 
 	flagSet.VisitAll(func(f *flag.Flag) {
-		if err != nil {
+		if len(errs) > 0 && !o.collect {
 			return
 		}
 
-		keyName := format(f.Name)
-		envName := name + keyName
+		value, variable, ok, err := o.find(name, f)
+		if err == nil && ok {
+			err = f.Value.Set(value)
+		}
+		if err != nil {
+			errs = append(errs, &Error{Variable: variable, Key: f.Name, Err: err})
+		}
+	})
 
-		value, ok := os.LookupEnv(envName)
-		if !ok {
-			return
+	switch {
+	case len(errs) == 0:
+		return nil
+	case o.collect:
+		return errs
+	default:
+		return errs[0]
+	}
+}
+
+// find returns the value for f from the first of its variables that is set, or
+// from a file as enabled by SecretFiles and Credentials, along with the name of
+// the variable it came from.
+func (o options) find(prefix string, f *flag.Flag) (string, string, bool, error) {
+	for _, v := range o.variables(prefix, f) {
+		value, ok := o.lookup(v)
+
+		if o.secretFiles {
+			filename, fileOK := o.lookup(v + fileSuffix)
+			if ok && fileOK {
+				return "", v, false, fmt.Errorf("both %s and %s%s are set", v, v, fileSuffix)
+			}
+			if fileOK {
+				value, err := readSecret(filename)
+				return value, v + fileSuffix, err == nil, err
+			}
 		}
 
-		err = f.Value.Set(value)
+		if ok {
+			return value, v, true, nil
+		}
+	}
+
+	if o.credentials {
+		if dir, ok := o.lookup(credentialsDirectory); ok && dir != "" {
+			value, err := readSecret(filepath.Join(dir, f.Name))
+			if os.IsNotExist(err) {
+				return "", "", false, nil
+			}
+			return value, credentialsDirectory, err == nil, err
+		}
+	}
+
+	return "", "", false, nil
+}
+
+// checkUnknown reports each variable that has the given prefix but matches no
+// flag, in sorted order.
+func (o options) checkUnknown(flagSet FlagSet, prefix string) error {
+	if o.unknown == nil || prefix == "" {
+		return nil
+	}
+
+	known := make(map[string]bool)
+	var candidates []string
+	flagSet.VisitAll(func(f *flag.Flag) {
+		for _, v := range o.variables(prefix, f) {
+			known[v] = true
+			candidates = append(candidates, v)
+			if o.secretFiles {
+				known[v+fileSuffix] = true
+			}
+		}
 	})
 
+	var unknown []string
+	for _, n := range o.names {
+		if strings.HasPrefix(n, prefix) && !known[n] {
+			unknown = append(unknown, n)
+		}
+	}
+	sort.Strings(unknown)
+
+	for _, n := range unknown {
+		err := &UnknownVariableError{
+			Name:        n,
+			Suggestions: suggest.Closest(n, candidates),
+		}
+		if err := o.unknown(err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Error describes a variable whose value could not be set.  Variable is the
+// name of the variable, or CREDENTIALS_DIRECTORY for a value read from a
+// systemd credential, and Key is the name of the flag.
+type Error struct {
+	Variable string
+	Key      string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("key '%s' from %s: %v", e.Key, e.Variable, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Errors is returned with the CollectErrors option, holding each Error in
+// the order of the flags.  errors.Is and errors.As consider each of them.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e Errors) Unwrap() []error { return e }
+
+// Check reports an error if the variables of two flags in flagSet have the same
+// name, given the same options as Parse, such as for the keys "A.B" and "A_B".
+// It should be called after the flags are registered.  If flagSet is nil, the
+// global flag.CommandLine FlagSet is used.
+func Check(flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	o := buildOptions(opts)
+	prefix := o.prefixOf(flagSet)
+
+	var err error
+	keys := make(map[string]string)
+	flagSet.VisitAll(func(f *flag.Flag) {
+		for _, v := range o.variables(prefix, f) {
+			if other, ok := keys[v]; ok && err == nil {
+				err = fmt.Errorf("keys '%s' and '%s' both use variable %s", other, f.Name, v)
+			}
+			keys[v] = f.Name
+		}
+	})
 	return err
 }
 
+// prefixOf returns the prefix of variables, followed by an underscore, or the
+// empty string if there is none.  By default this is the formatted name of
+// flagSet.
+func (o options) prefixOf(flagSet FlagSet) string {
+	var name string
+	if o.prefix != nil {
+		name = *o.prefix
+	} else if n, ok := flagSet.(namer); ok {
+		name = format(path.Base(n.Name()))
+	}
+	if name != "" {
+		name += "_"
+	}
+	return name
+}
+
+// variables returns the names of the environment variables for f, in order of
+// preference, given the prefix of its flag set.
+func (o options) variables(prefix string, f *flag.Flag) []string {
+	if v, ok := f.Value.(Variabler); ok {
+		if names := v.EnvNames(); len(names) > 0 {
+			return names
+		}
+	}
+	return []string{prefix + o.naming(f.Name)}
+}
+
 func format(x string) string {
 	return strings.ToUpper(replacer.Replace(strings.TrimSpace(x)))
 }
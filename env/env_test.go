@@ -4,9 +4,19 @@ import (
 	"errors"
 	"flag"
 	"os"
+	"strings"
 	"testing"
 )
 
+// environ returns vars as a list of "NAME=VALUE" strings.
+func environ(vars map[string]string) []string {
+	var list []string
+	for k, v := range vars {
+		list = append(list, k+"="+v)
+	}
+	return list
+}
+
 func makeFlagSet(name string, flags map[string]interface{}) *flag.FlagSet {
 	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
 
@@ -27,6 +37,8 @@ func makeFlagSet(name string, flags map[string]interface{}) *flag.FlagSet {
 }
 
 func TestParseValid(t *testing.T) {
+	t.Parallel()
+
 	suite := []struct {
 		name  string
 		env   map[string]string
@@ -145,17 +157,13 @@ func TestParseValid(t *testing.T) {
 	}
 
 	for _, item := range suite {
+		item := item
 		t.Run(item.name, func(t *testing.T) {
-			os.Clearenv()
-			for k, v := range item.env {
-				os.Setenv(k, v)
-			}
+			t.Parallel()
 
 			flagSet := makeFlagSet(item.name, item.start)
-			// In order to test global FlagSet
-			flag.CommandLine = flagSet
 
-			if err := Parse(nil); err != nil {
+			if err := Parse(flagSet, Environ(environ(item.env))); err != nil {
 				t.Error("unexpected error:", err)
 			}
 
@@ -170,27 +178,293 @@ func TestParseValid(t *testing.T) {
 	}
 }
 
+// registry is a minimal FlagSet that is not a flag.FlagSet.
+type registry map[string]*flag.Flag
+
+func (r registry) Var(value flag.Value, name string, usage string) {
+	r[name] = &flag.Flag{Name: name, Usage: usage, Value: value, DefValue: value.String()}
+}
+
+func (r registry) Lookup(name string) *flag.Flag { return r[name] }
+
+func (r registry) VisitAll(fn func(*flag.Flag)) {
+	for _, f := range r {
+		fn(f)
+	}
+}
+
+func TestParseRegistry(t *testing.T) {
+	t.Parallel()
+
+	reg := registry{}
+	var name string
+	reg.Var(flagValue{&name}, "Database.Name", "")
+
+	if err := Parse(reg, Environ([]string{"DATABASE_NAME=sushi"})); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if name != "sushi" {
+		t.Error("unexpected name:", name)
+	}
+}
+
+type flagValue struct{ s *string }
+
+func (v flagValue) Set(s string) error {
+	*v.s = s
+	return nil
+}
+
+func (v flagValue) String() string {
+	if v.s == nil {
+		return ""
+	}
+	return *v.s
+}
+
+// namedValue is a flagValue with its own environment variables.
+type namedValue struct {
+	flagValue
+	names []string
+}
+
+func (v namedValue) EnvNames() []string { return v.names }
+
+func TestParseVariabler(t *testing.T) {
+	t.Parallel()
+
+	suite := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"first", map[string]string{"PORT": "1", "HTTP_PORT": "2"}, "1"},
+		{"fallback", map[string]string{"HTTP_PORT": "2"}, "2"},
+		{"derived name ignored", map[string]string{"TESTPARSEVARIABLER_SERVER_PORT": "3"}, ""},
+	}
+
+	for _, item := range suite {
+		item := item
+		t.Run(item.name, func(t *testing.T) {
+			t.Parallel()
+
+			var port string
+			flagSet := flag.NewFlagSet("TestParseVariabler", flag.ContinueOnError)
+			flagSet.Var(namedValue{flagValue{&port}, []string{"PORT", "HTTP_PORT"}}, "Server.Port", "")
+
+			if err := Parse(flagSet, Environ(environ(item.env))); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if port != item.expected {
+				t.Errorf("expected %q, got %q", item.expected, port)
+			}
+		})
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	t.Parallel()
+
+	suite := []struct {
+		name     string
+		opts     []Option
+		variable string
+	}{
+		{"default", nil, "MY_APP_DATABASE_PORT"},
+		{"prefix", []Option{Prefix("SVC")}, "SVC_DATABASE_PORT"},
+		{"empty prefix", []Option{Prefix("")}, "DATABASE_PORT"},
+		{"naming", []Option{Naming(func(key string) string {
+			return strings.Replace(key, ".", "__", -1)
+		})}, "MY_APP_Database__Port"},
+	}
+
+	for _, item := range suite {
+		item := item
+		t.Run(item.name, func(t *testing.T) {
+			t.Parallel()
+
+			flagSet := flag.NewFlagSet("/usr/bin/my app", flag.ContinueOnError)
+			port := flagSet.Int("Database.Port", 0, "")
+
+			opts := append(item.opts, Environ([]string{item.variable + "=5432"}))
+			if err := Parse(flagSet, opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if *port != 5432 {
+				t.Errorf("%s not read", item.variable)
+			}
+		})
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	t.Parallel()
+
+	flagSet := flag.NewFlagSet("app", flag.ContinueOnError)
+	host := flagSet.String("Database.Host", "", "")
+	flagSet.Var(namedValue{flagValue{new(string)}, []string{"APP_PORT_NUMBER"}}, "Port", "")
+
+	env := Environ([]string{
+		"APP_DATABSE_HOST=typo",
+		"APP_DATABASE_HOST=db",
+		"APP_PORT_NUMBER=80",
+		"APPLICATION=other",
+	})
+
+	err := Parse(flagSet, env, Strict())
+	if err == nil {
+		t.Error("expected unknown variable error")
+	} else if err.Error() != "unknown variable 'APP_DATABSE_HOST', did you mean 'APP_DATABASE_HOST'?" {
+		t.Error("unexpected error:", err.Error())
+	}
+	if *host != "" {
+		t.Error("flag set despite error")
+	}
+
+	var unknown []string
+	err = Parse(flagSet, env, OnUnknown(func(err *UnknownVariableError) {
+		unknown = append(unknown, err.Name)
+	}))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if strings.Join(unknown, ",") != "APP_DATABSE_HOST" || *host != "db" {
+		t.Errorf("unexpected unknown variables %v, host %q", unknown, *host)
+	}
+
+	if err := Parse(flagSet, env, Strict(), Prefix("")); err != nil {
+		t.Error("unexpected error without prefix:", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	flagSet := flag.NewFlagSet("app", flag.ContinueOnError)
+	flagSet.String("A.B", "", "")
+	flagSet.String("A_C", "", "")
+
+	if err := Check(flagSet); err != nil {
+		t.Error("unexpected error:", err)
+	}
+
+	flagSet.String("A_B", "", "")
+
+	err := Check(flagSet)
+	if err == nil {
+		t.Error("expected collision error")
+	} else if err.Error() != "keys 'A.B' and 'A_B' both use variable APP_A_B" {
+		t.Error("unexpected error:", err.Error())
+	}
+
+	if err := Check(flagSet, Naming(func(key string) string { return key })); err != nil {
+		t.Error("unexpected error with naming:", err)
+	}
+}
+
+func TestParseEnvironment(t *testing.T) {
+	flagSet := flag.NewFlagSet("TestParseEnvironment", flag.ContinueOnError)
+	name := flagSet.String("Name", "", "")
+	port := flagSet.Int("Port", 0, "")
+
+	// In order to test the process environment and global FlagSet
+	os.Setenv("TESTPARSEENVIRONMENT_NAME", "Sushi")
+	defer os.Unsetenv("TESTPARSEENVIRONMENT_NAME")
+	commandLine := flag.CommandLine
+	flag.CommandLine = flagSet
+	defer func() { flag.CommandLine = commandLine }()
+
+	if err := Parse(nil); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if *name != "Sushi" {
+		t.Errorf("unexpected name %q", *name)
+	}
+
+	// A nil *flag.FlagSet selects the global FlagSet, as nil does
+	var typedNil *flag.FlagSet
+	*name = ""
+	if err := Parse(typedNil); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if *name != "Sushi" {
+		t.Errorf("unexpected name %q from a nil *flag.FlagSet", *name)
+	}
+
+	lookup := func(key string) (string, bool) {
+		if key == "TESTPARSEENVIRONMENT_PORT" {
+			return "80", true
+		}
+		return "", false
+	}
+	if err := Parse(flagSet, LookupFunc(lookup), Strict()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if *port != 80 {
+		t.Errorf("unexpected port %d", *port)
+	}
+
+	env := Environ([]string{"TESTPARSEENVIRONMENT_PORT=1", "IGNORED", "TESTPARSEENVIRONMENT_PORT=2=3"})
+	if err := Parse(flagSet, env); err == nil {
+		t.Error("expected parse error for 2=3")
+	}
+}
+
 type badvar struct{}
 
 func (badvar) Set(string) error { return errors.New("test") }
 func (badvar) String() string   { return "" }
 
 func TestParseBadValue(t *testing.T) {
+	t.Parallel()
+
 	flagSet := flag.NewFlagSet("TestParseBadValue", flag.ContinueOnError)
 	flagSet.Var(badvar{}, "Bar", "")
 	flagSet.Var(badvar{}, "Baz", "")
 	flagSet.Var(badvar{}, "Foo", "")
 
-	os.Clearenv()
-	os.Setenv("TESTPARSEBADVALUE_BAZ", "BAR")
-
-	err := Parse(flagSet)
+	err := Parse(flagSet, Environ([]string{"TESTPARSEBADVALUE_BAZ=BAR"}))
 
 	if err == nil {
 		t.Error("expected set error")
 	} else {
-		if err.Error() != "test" {
+		if err.Error() != "key 'Baz' from TESTPARSEBADVALUE_BAZ: test" {
 			t.Error("unexpected error:", err.Error())
 		}
 	}
 }
+
+func TestParseCollectErrors(t *testing.T) {
+	t.Parallel()
+
+	flagSet := flag.NewFlagSet("app", flag.ContinueOnError)
+	flagSet.Var(badvar{}, "Bar", "")
+	flagSet.Int("Count", 0, "")
+	name := flagSet.String("Name", "", "")
+	flagSet.Var(badvar{}, "Other", "")
+
+	env := Environ([]string{"APP_BAR=1", "APP_COUNT=x", "APP_NAME=ok", "APP_OTHER=2"})
+
+	err := Parse(flagSet, env, CollectErrors())
+	if err == nil {
+		t.Fatal("expected set errors")
+	}
+
+	expected := `key 'Bar' from APP_BAR: test
+key 'Count' from APP_COUNT: parse error
+key 'Other' from APP_OTHER: test`
+	if err.Error() != expected {
+		t.Errorf("unexpected error\nexpected: %s\n  actual: %s", expected, err.Error())
+	}
+	if *name != "ok" {
+		t.Errorf("unexpected name %q", *name)
+	}
+
+	var envErr *Error
+	if !errors.As(err, &envErr) || envErr.Variable != "APP_BAR" || envErr.Key != "Bar" {
+		t.Errorf("unexpected error %#v", envErr)
+	}
+	if errs, ok := err.(Errors); !ok || len(errs) != 3 {
+		t.Errorf("unexpected errors %#v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package env
+
+import (
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+)
+
+// FlagSet is the interface for finding the flags that configuration values
+// are assigned to.  It requires only the Lookup and VisitAll methods, so a
+// *flag.FlagSet meets it, as does any other type that provides them.
+// If the FlagSet also has a Name method, as flag.FlagSet does, the name is
+// used to prefix environment variables.
+type FlagSet = flagutil.FlagSet
+
+type namer interface {
+	Name() string
+}
+
+// Variabler is implemented by flag values that name their own environment
+// variables, such as those built by goflagbuilder.Into for fields with an "env"
+// struct tag.  The names are used as given, in place of the derived name.
+type Variabler interface {
+	EnvNames() []string
+}
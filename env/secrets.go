@@ -0,0 +1,43 @@
+package env
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// fileSuffix is appended to a variable to name a file holding its value.
+const fileSuffix = "_FILE"
+
+// credentialsDirectory is the variable in which systemd gives the directory of
+// a service's credentials.
+const credentialsDirectory = "CREDENTIALS_DIRECTORY"
+
+// SecretFiles reads the value of a variable from the file named by the same
+// variable with the suffix "_FILE", such as APP_DB_PASSWORD_FILE for
+// APP_DB_PASSWORD, if that is set.  One trailing newline is trimmed from the
+// file.  It is an error for both variables to be set.
+func SecretFiles() Option {
+	return func(o *options) { o.secretFiles = true }
+}
+
+// Credentials reads the value of a flag with no variable set from the file
+// named by its key in the directory given by $CREDENTIALS_DIRECTORY, as
+// systemd's LoadCredential= provides, if that is set.  Keys without such a file
+// are left unset.  One trailing newline is trimmed from the file.
+func Credentials() Option {
+	return func(o *options) { o.credentials = true }
+}
+
+// readSecret returns the content of filename without one trailing newline.
+func readSecret(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	value := string(data)
+	if strings.HasSuffix(value, "\r\n") {
+		return value[:len(value)-2], nil
+	}
+	return strings.TrimSuffix(value, "\n"), nil
+}
@@ -0,0 +1,114 @@
+package env
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSecrets(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	if err != nil {
+		t.Fatal("failed to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"password":          "hunter2\n",
+		"token":             "abc\r\n",
+		"Database.Password": "credential\n\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal("failed to write secret:", err)
+		}
+	}
+
+	suite := []struct {
+		name     string
+		opts     []Option
+		env      map[string]string
+		password string
+		token    string
+	}{
+		{
+			name:     "plain",
+			opts:     []Option{SecretFiles()},
+			env:      map[string]string{"APP_DATABASE_PASSWORD": "plain"},
+			password: "plain",
+		},
+		{
+			name: "file",
+			opts: []Option{SecretFiles(), Strict()},
+			env: map[string]string{
+				"APP_DATABASE_PASSWORD_FILE": filepath.Join(dir, "password"),
+				"APP_TOKEN_FILE":             filepath.Join(dir, "token"),
+			},
+			password: "hunter2",
+			token:    "abc",
+		},
+		{
+			name:     "file disabled",
+			env:      map[string]string{"APP_DATABASE_PASSWORD_FILE": filepath.Join(dir, "password")},
+			password: "",
+		},
+		{
+			name:     "credentials",
+			opts:     []Option{Credentials()},
+			env:      map[string]string{"CREDENTIALS_DIRECTORY": dir},
+			password: "credential\n",
+		},
+		{
+			name: "variable over credentials",
+			opts: []Option{Credentials()},
+			env: map[string]string{
+				"CREDENTIALS_DIRECTORY": dir,
+				"APP_DATABASE_PASSWORD": "plain",
+			},
+			password: "plain",
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet("app", flag.ContinueOnError)
+			password := flagSet.String("Database.Password", "", "")
+			token := flagSet.String("Token", "", "")
+
+			opts := append(item.opts, Environ(environ(item.env)))
+			if err := Parse(flagSet, opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if *password != item.password || *token != item.token {
+				t.Errorf("unexpected values %q %q", *password, *token)
+			}
+		})
+	}
+}
+
+func TestParseSecretsInvalid(t *testing.T) {
+	t.Parallel()
+
+	flagSet := flag.NewFlagSet("app", flag.ContinueOnError)
+	flagSet.String("Password", "", "")
+
+	err := Parse(flagSet, SecretFiles(), Environ([]string{
+		"APP_PASSWORD=plain",
+		"APP_PASSWORD_FILE=/goflagbuilder-bad-test",
+	}))
+	if err == nil {
+		t.Error("expected conflict error")
+	} else if err.Error() != "key 'Password' from APP_PASSWORD: both APP_PASSWORD and APP_PASSWORD_FILE are set" {
+		t.Error("unexpected error:", err.Error())
+	}
+
+	err = Parse(flagSet, SecretFiles(), Environ([]string{"APP_PASSWORD_FILE=/goflagbuilder-bad-test"}))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Error("unexpected error:", err)
+	}
+}
@@ -0,0 +1,309 @@
+package goflagbuilder
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/conf"
+	"github.com/BellerophonMobile/goflagbuilder/v2/env"
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+	"github.com/BellerophonMobile/goflagbuilder/v2/json"
+	"github.com/BellerophonMobile/goflagbuilder/v2/properties"
+	"github.com/BellerophonMobile/goflagbuilder/v2/toml"
+	"github.com/BellerophonMobile/goflagbuilder/v2/yaml"
+)
+
+// Flags is the interface for finding the flags that configuration values are
+// assigned to.  It requires only the Lookup and VisitAll methods, so a
+// *flag.FlagSet meets it, as does any other type that provides them.  It is
+// the same type as the FlagSet of each configuration subpackage.
+type Flags = flagutil.FlagSet
+
+// Format describes a configuration file format for ParseFile.
+type Format struct {
+	// Name identifies the format in errors.
+	Name string
+
+	// Extensions lists the file extensions of the format, including
+	// the leading dot, such as ".toml".  They are matched without
+	// regard to case.
+	Extensions []string
+
+	// Sniff reports whether the start of a file looks like this format.
+	// It is used for files whose extension matches no format, and may be
+	// nil.
+	Sniff func(head []byte) bool
+
+	// Parse reads a document, setting values to matching flags.
+	Parse func(in io.Reader, flags Flags, opts ParseOptions) error
+
+	// ParseProfile reads only the overrides for the named profile from a
+	// document, such as the "[profile.NAME]" sections of conf files.  It
+	// may be nil for formats without profiles.
+	ParseProfile func(in io.Reader, flags Flags, profile string, opts ParseOptions) error
+}
+
+// ParseOption configures optional behavior of ParseFile and ParseProfile.
+type ParseOption func(*ParseOptions)
+
+// ParseOptions holds the options given to ParseFile, for a Format to apply.
+type ParseOptions struct {
+	// OnUnknown, if not nil, is called for each key that matches no flag,
+	// with the error the format's package reports for it, such as a
+	// *conf.UnknownKeyError, and parsing continues.  Otherwise such keys
+	// are an error, except in dotenv files, which ignore them as env.Parse
+	// does.
+	OnUnknown func(err error)
+}
+
+// IgnoreUnknown skips keys that do not match any flag.
+func IgnoreUnknown() ParseOption {
+	return OnUnknown(func(error) {})
+}
+
+// OnUnknown calls fn for each key that does not match any flag, and then
+// continues parsing.  This may be used to log a warning.
+func OnUnknown(fn func(err error)) ParseOption {
+	return func(o *ParseOptions) { o.OnUnknown = fn }
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = []Format{
+		{
+			Name:       "json",
+			Extensions: []string{".json"},
+			Sniff:      sniffJSON,
+			Parse: func(in io.Reader, flags Flags, opts ParseOptions) error {
+				if opts.OnUnknown == nil {
+					return json.Parse(in, flags)
+				}
+				return json.Parse(in, flags, json.OnUnknown(func(err *json.UnknownKeyError) { opts.OnUnknown(err) }))
+			},
+		},
+		{
+			Name:       "toml",
+			Extensions: []string{".toml"},
+			Sniff:      sniffTOML,
+			Parse: func(in io.Reader, flags Flags, opts ParseOptions) error {
+				if opts.OnUnknown == nil {
+					return toml.Parse(in, flags)
+				}
+				return toml.Parse(in, flags, toml.OnUnknown(func(err *toml.UnknownKeyError) { opts.OnUnknown(err) }))
+			},
+		},
+		{
+			Name:       "dotenv",
+			Extensions: []string{".env"},
+			Sniff:      sniffDotenv,
+			Parse:      func(in io.Reader, flags Flags, _ ParseOptions) error { return env.ParseReader(in, flags) },
+		},
+		{
+			Name:       "yaml",
+			Extensions: []string{".yaml", ".yml"},
+			Sniff:      sniffYAML,
+			Parse: func(in io.Reader, flags Flags, opts ParseOptions) error {
+				if opts.OnUnknown == nil {
+					return yaml.Parse(in, flags)
+				}
+				return yaml.Parse(in, flags, yaml.OnUnknown(func(err *yaml.UnknownKeyError) { opts.OnUnknown(err) }))
+			},
+		},
+		{
+			Name:       "properties",
+			Extensions: []string{".properties"},
+			Sniff:      sniffProperties,
+			Parse: func(in io.Reader, flags Flags, opts ParseOptions) error {
+				if opts.OnUnknown == nil {
+					return properties.Parse(in, flags)
+				}
+				return properties.Parse(in, flags, properties.OnUnknown(func(err *properties.UnknownKeyError) { opts.OnUnknown(err) }))
+			},
+		},
+		{
+			Name:       "conf",
+			Extensions: []string{".conf", ".cfg"},
+			Sniff:      sniffConf,
+			Parse: func(in io.Reader, flags Flags, opts ParseOptions) error {
+				return conf.Parse(in, flags, confOptions(opts)...)
+			},
+			ParseProfile: func(in io.Reader, flags Flags, profile string, opts ParseOptions) error {
+				return conf.Parse(in, flags, append(confOptions(opts), conf.OnlyProfile(profile))...)
+			},
+		},
+	}
+)
+
+func confOptions(opts ParseOptions) []conf.Option {
+	if opts.OnUnknown == nil {
+		return nil
+	}
+	return []conf.Option{conf.OnUnknown(func(err *conf.UnknownKeyError) { opts.OnUnknown(err) })}
+}
+
+// RegisterFormat adds a format for ParseFile to recognize.  Formats registered
+// later take precedence over those registered earlier and over the built in
+// conf, JSON, TOML, YAML, dotenv, and properties formats.
+func RegisterFormat(format Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	formats = append([]Format{format}, formats...)
+}
+
+// DetectFormat chooses the format of a file from its name, or failing that
+// from head, the first bytes of its content.
+func DetectFormat(filename string, head []byte) (Format, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	ext := filepath.Ext(filename)
+	for _, format := range formats {
+		for _, e := range format.Extensions {
+			if strings.EqualFold(ext, e) {
+				return format, nil
+			}
+		}
+	}
+
+	for _, format := range formats {
+		if format.Sniff != nil && format.Sniff(head) {
+			return format, nil
+		}
+	}
+
+	return Format{}, fmt.Errorf("cannot determine format of %s", filename)
+}
+
+// sniffLength is the number of bytes of a file given to DetectFormat.
+const sniffLength = 4096
+
+// ParseFile reads the configuration file indicated by filename, setting values
+// to matching flags in the given flags.  The format of the file is chosen by
+// DetectFormat.  If flags is nil, the global flag.CommandLine FlagSet is used.
+func ParseFile(filename string, flags Flags, opts ...ParseOption) error {
+	return parseFile(filename, flags, "", opts)
+}
+
+// ParseProfile reads the overrides for the named profile from the
+// configuration file indicated by filename, as ParseFile does for the rest of
+// the file.  Files in formats without profiles are opened, but otherwise
+// ignored.
+func ParseProfile(filename string, flags Flags, profile string, opts ...ParseOption) error {
+	return parseFile(filename, flags, profile, opts)
+}
+
+func parseFile(filename string, flags Flags, profile string, opts []ParseOption) error {
+	if flagutil.IsNil(flags) {
+		flags = flag.CommandLine
+	}
+
+	var o ParseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	reader := bufio.NewReaderSize(in, sniffLength)
+	head, err := reader.Peek(sniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+
+	format, err := DetectFormat(filename, head)
+	if err != nil {
+		return err
+	}
+
+	parse := format.Parse
+	if profile != "" {
+		if format.ParseProfile == nil {
+			return nil
+		}
+		parse = func(in io.Reader, flags Flags, opts ParseOptions) error {
+			return format.ParseProfile(in, flags, profile, opts)
+		}
+	}
+
+	if err := parse(reader, flags, o); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
+
+var (
+	dotenvLine     = regexp.MustCompile(`^(export\s+)?[A-Z_][A-Z0-9_]*\s*=`)
+	yamlLine       = regexp.MustCompile(`^("[^"]*"|[^\s=:"]+)\s*:(\s|$)`)
+	propertiesLine = regexp.MustCompile(`^[^\s=:]+:\S`)
+	confLine       = regexp.MustCompile(`^(!?[^\s=#]+|[^=#]+=.*)$`)
+)
+
+// firstLine returns the first line of head that is neither blank nor a "#"
+// comment, trimmed of surrounding whitespace.
+func firstLine(head []byte) (string, bool) {
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line[0] != '#' {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+func sniffJSON(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("{"))
+}
+
+// sniffTOML looks for a table header.  Headers of profile tables are left to
+// sniffConf, as conf files have them too.
+func sniffTOML(head []byte) bool {
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && !strings.HasPrefix(line, "[profile.") {
+			return true
+		}
+	}
+	return false
+}
+
+func sniffDotenv(head []byte) bool {
+	line, ok := firstLine(head)
+	return ok && dotenvLine.MatchString(line)
+}
+
+func sniffYAML(head []byte) bool {
+	line, ok := firstLine(head)
+	return ok && (line == "---" || yamlLine.MatchString(line))
+}
+
+// sniffProperties looks for "!" comments, which conf does not have, or a
+// colon separator, which YAML would require a space after.
+func sniffProperties(head []byte) bool {
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "!" || strings.HasPrefix(line, "! ") {
+			return true
+		}
+	}
+
+	line, ok := firstLine(head)
+	return ok && propertiesLine.MatchString(line)
+}
+
+func sniffConf(head []byte) bool {
+	line, ok := firstLine(head)
+	return ok && confLine.MatchString(line)
+}
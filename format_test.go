@@ -0,0 +1,220 @@
+package goflagbuilder
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	suite := []struct {
+		name     string
+		filename string
+		head     string
+		format   string
+	}{
+		{"conf extension", "app.conf", "", "conf"},
+		{"json extension", "app.JSON", "", "json"},
+		{"toml extension", "app.toml", "", "toml"},
+		{"yaml extension", "app.yaml", "", "yaml"},
+		{"yml extension", "app.yml", "", "yaml"},
+		{"dotenv extension", ".env", "", "dotenv"},
+		{"properties extension", "app.properties", "", "properties"},
+		{"sniff json", "app", "\n  {\"Foo\": 1}", "json"},
+		{"sniff toml", "app", "Label = \"x\"\n[Foo]\nPort = 1", "toml"},
+		{"sniff dotenv", "app", "# settings\nexport APP_PORT=1", "dotenv"},
+		{"sniff yaml", "app", "Foo:\n  Port: 1", "yaml"},
+		{"sniff yaml document", "app", "---\nFoo: 1", "yaml"},
+		{"sniff properties comment", "app", "! comment\nFoo = 1", "properties"},
+		{"sniff properties colon", "app", "Foo.Port:1", "properties"},
+		{"sniff conf", "app", "# comment\nFoo.Port = 1", "conf"},
+		{"sniff conf reset", "app", "!Foo.Port", "conf"},
+		{"sniff conf profile", "app", "Foo.Port = 1\n\n[profile.prod]\nFoo.Port = 2", "conf"},
+		{"sniff toml array table", "app", "[profile.prod]\nPort = 2\n[[Servers]]", "toml"},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			format, err := DetectFormat(item.filename, []byte(item.head))
+			require.NoError(t, err)
+			assert.Equal(t, item.format, format.Name)
+		})
+	}
+
+	_, err := DetectFormat("app", []byte("   "))
+	assert.EqualError(t, err, "cannot determine format of app")
+}
+
+func TestParseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	suite := []struct {
+		filename string
+		content  string
+		domain   string
+	}{
+		{"app.conf", "Foo.Port = 1234\nFoo.Domain = conf.example", "conf.example"},
+		{"app.json", `{"Foo": {"Port": 1234, "Domain": "json.example"}}`, "json.example"},
+		{"app.toml", "[Foo]\nPort = 1234\nDomain = \"toml.example\"", "toml.example"},
+		{"app.yaml", "Foo:\n  Port: 1234\n  Domain: yaml.example", "yaml.example"},
+		{"app.env", "TEST_FOO_PORT=1234\nTEST_FOO_DOMAIN=dotenv.example", "dotenv.example"},
+		{"app.properties", "Foo.Port: 1234\nFoo.Domain = properties.example", "properties.example"},
+		{"sniffed", "Foo:\n  Port: 1234\n  Domain: sniffed.example", "sniffed.example"},
+	}
+
+	for _, item := range suite {
+		t.Run(item.filename, func(t *testing.T) {
+			filename := filepath.Join(dir, item.filename)
+			require.NoError(t, ioutil.WriteFile(filename, []byte(item.content), 0644))
+
+			foo := &foocomponent{}
+			flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+			require.NoError(t, Into(flagSet, map[string]interface{}{"Foo": foo}))
+
+			require.NoError(t, ParseFile(filename, flagSet))
+			assert.Equal(t, 1234, foo.Port)
+			assert.Equal(t, item.domain, foo.Domain)
+		})
+	}
+}
+
+func TestParseFile_TOMLInto(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.toml")
+	content := `Started = 2018-10-15T12:30:00Z
+
+[[Servers]]
+Domain = "a.example"
+
+[[Servers]]
+Domain = "b.example"
+Port = 8080
+`
+	require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+	// Arrays of tables are read into a map holding an entry for each index.
+	conf := &struct {
+		Started time.Time
+		Servers map[string]*foocomponent
+	}{
+		Servers: map[string]*foocomponent{"0": {}, "1": {}},
+	}
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	require.NoError(t, Into(flagSet, conf))
+
+	require.NoError(t, ParseFile(filename, flagSet))
+	assert.Equal(t, time.Date(2018, 10, 15, 12, 30, 0, 0, time.UTC), conf.Started)
+	assert.Equal(t, &foocomponent{Domain: "a.example"}, conf.Servers["0"])
+	assert.Equal(t, &foocomponent{Domain: "b.example", Port: 8080}, conf.Servers["1"])
+}
+
+func TestParseFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.conf")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("Foo.Prot = 1"), 0644))
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	require.NoError(t, Into(flagSet, map[string]interface{}{"Foo": &foocomponent{}}))
+
+	err = ParseFile(filename, flagSet)
+	assert.EqualError(t, err, filename+": unknown key 'Foo.Prot' on line 1, did you mean 'Foo.Port'?")
+
+	err = ParseFile(filepath.Join(dir, "missing.conf"), flagSet)
+	assert.True(t, os.IsNotExist(err), "unexpected error: %v", err)
+}
+
+func TestParseFileUnknown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	suite := []struct {
+		filename string
+		content  string
+	}{
+		{"app.conf", "Foo.Prot = 1\nFoo.Domain = sushi.example"},
+		{"app.json", `{"Foo": {"Prot": 1, "Domain": "sushi.example"}}`},
+		{"app.toml", "[Foo]\nProt = 1\nDomain = \"sushi.example\""},
+		{"app.yaml", "Foo:\n  Prot: 1\n  Domain: sushi.example"},
+		{"app.properties", "Foo.Prot = 1\nFoo.Domain = sushi.example"},
+	}
+
+	for _, item := range suite {
+		t.Run(item.filename, func(t *testing.T) {
+			filename := filepath.Join(dir, item.filename)
+			require.NoError(t, ioutil.WriteFile(filename, []byte(item.content), 0644))
+
+			foo := &foocomponent{}
+			flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+			require.NoError(t, Into(flagSet, map[string]interface{}{"Foo": foo}))
+
+			assert.Error(t, ParseFile(filename, flagSet))
+
+			var unknown []error
+			require.NoError(t, ParseFile(filename, flagSet, OnUnknown(func(err error) {
+				unknown = append(unknown, err)
+			})))
+			assert.Equal(t, "sushi.example", foo.Domain)
+			if assert.Len(t, unknown, 1) {
+				assert.Contains(t, unknown[0].Error(), "did you mean 'Foo.Port'?")
+			}
+
+			require.NoError(t, ParseFile(filename, flagSet, IgnoreUnknown()))
+		})
+	}
+}
+
+var errUpper = errors.New("upper")
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat(Format{
+		Name:       "upper",
+		Extensions: []string{".upper"},
+		Parse: func(in io.Reader, flags Flags, opts ParseOptions) error {
+			data, err := ioutil.ReadAll(in)
+			if err != nil {
+				return err
+			}
+			for _, line := range strings.Fields(string(data)) {
+				if f := flags.Lookup(line); f != nil {
+					f.Value.Set(strings.ToUpper(line))
+				} else {
+					return errUpper
+				}
+			}
+			return nil
+		},
+	})
+
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.upper")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("Domain\nOther"), 0644))
+
+	foo := &foocomponent{}
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	require.NoError(t, Into(flagSet, foo))
+
+	err = ParseFile(filename, flagSet)
+	assert.True(t, errors.Is(err, errUpper), "unexpected error: %v", err)
+	assert.Equal(t, "DOMAIN", foo.Domain)
+}
@@ -2,6 +2,8 @@ module github.com/BellerophonMobile/goflagbuilder/v2
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pelletier/go-toml v1.9.5
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/testify v1.2.2
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,33 @@
+// Package flagtest holds the flag values shared by the tests of the parser
+// packages.
+package flagtest
+
+import (
+	"strconv"
+	"strings"
+)
+
+// IntSlice is a flag value that appends each integer it is set to.
+type IntSlice []int
+
+// Set appends the integer v to the slice.
+func (s *IntSlice) Set(v string) error {
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return err
+	}
+	*s = append(*s, i)
+	return nil
+}
+
+// Get returns the elements of the slice as an []int.
+func (s *IntSlice) Get() interface{} { return []int(*s) }
+
+// String returns the elements of the slice in brackets, separated by commas.
+func (s *IntSlice) String() string {
+	var items []string
+	for _, i := range *s {
+		items = append(items, strconv.Itoa(i))
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
@@ -0,0 +1,92 @@
+// Package flagutil holds the helpers shared by the parsers for inspecting the
+// flags of a FlagSet.
+package flagutil
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+)
+
+// FlagSet is the interface for finding the flags that configuration values
+// are assigned to.  It requires only the Lookup and VisitAll methods, which
+// *flag.FlagSet provides.  Each parser package re-exports it as its own
+// FlagSet.
+type FlagSet interface {
+	Lookup(name string) *flag.Flag
+	VisitAll(fn func(*flag.Flag))
+}
+
+// Visitor is met by FlagSet.
+type Visitor interface {
+	VisitAll(fn func(*flag.Flag))
+}
+
+// IsNil reports whether flagSet is nil, including a nil *flag.FlagSet held in
+// the interface, so that the parsers may fall back to flag.CommandLine.
+func IsNil(flagSet Visitor) bool {
+	if flagSet == nil {
+		return true
+	}
+	fs, ok := flagSet.(*flag.FlagSet)
+	return ok && fs == nil
+}
+
+// Names returns the names of every flag in flagSet.
+func Names(flagSet Visitor) []string {
+	var names []string
+	flagSet.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	return names
+}
+
+// IsBool reports whether value is a boolean flag, which may be given without
+// a value.
+func IsBool(value flag.Value) bool {
+	b, ok := value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// Multiple reports whether a flag value may hold several elements.  Values
+// that cannot say are assumed to.
+func Multiple(value flag.Value) bool {
+	getter, ok := value.(flag.Getter)
+	if !ok {
+		return true
+	}
+	return reflect.ValueOf(getter.Get()).Kind() == reflect.Slice
+}
+
+// Clear removes every element of a value that holds several and has a Clear
+// method, as those built by goflagbuilder.Into do.  Other values are left
+// alone.
+func Clear(value flag.Value) error {
+	if !Multiple(value) {
+		return nil
+	}
+	if clearer, ok := value.(interface{ Clear() error }); ok {
+		return clearer.Clear()
+	}
+	return nil
+}
+
+// SliceItems returns the elements of a value whose Get method returns a
+// slice, formatted as strings.
+func SliceItems(value flag.Value) ([]string, bool) {
+	getter, ok := value.(flag.Getter)
+	if !ok {
+		return nil, false
+	}
+
+	r := reflect.ValueOf(getter.Get())
+	if r.Kind() != reflect.Slice {
+		return nil, false
+	}
+
+	items := make([]string, r.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(r.Index(i).Interface())
+	}
+	return items, true
+}
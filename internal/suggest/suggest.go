@@ -0,0 +1,81 @@
+// Package suggest finds likely intended names for a misspelled key.
+package suggest
+
+import (
+	"sort"
+	"strings"
+)
+
+// Closest returns the candidates nearest to name by case-insensitive edit
+// distance, sorted by name. Only candidates within a distance proportional to
+// the length of name are considered, so unrelated names are never suggested.
+func Closest(name string, candidates []string) []string {
+	limit := len(name) / 3
+	if limit < 1 {
+		limit = 1
+	}
+
+	var names []string
+	lower := strings.ToLower(name)
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+
+		d := distance(lower, strings.ToLower(c))
+		if d < limit {
+			limit = d
+			names = names[:0]
+		}
+		if d == limit {
+			names = append(names, c)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Format renders suggestions as a "did you mean" phrase, or the empty string
+// if there are none.
+func Format(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return "did you mean '" + strings.Join(suggestions, "' or '") + "'?"
+}
+
+// distance computes the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
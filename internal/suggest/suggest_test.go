@@ -0,0 +1,42 @@
+package suggest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"FieldA", "FieldB", "Database.Host", "Database.Port"}
+
+	suite := []struct {
+		name     string
+		key      string
+		expected []string
+	}{
+		{"unrelated", "Foo", nil},
+		{"case", "fielda", []string{"FieldA"}},
+		{"transposed", "Databsae.Host", []string{"Database.Host"}},
+		{"ambiguous", "FieldC", []string{"FieldA", "FieldB"}},
+		{"exact", "FieldA", []string{"FieldB"}},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			actual := Closest(item.key, candidates)
+			if !reflect.DeepEqual(item.expected, actual) {
+				t.Errorf("suggestions not equal\nexpected: %v\n  actual: %v", item.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if s := Format(nil); s != "" {
+		t.Error("unexpected suggestion:", s)
+	}
+
+	expected := "did you mean 'FieldA' or 'FieldB'?"
+	if s := Format([]string{"FieldA", "FieldB"}); s != expected {
+		t.Errorf("format not equal\nexpected: %s\n  actual: %s", expected, s)
+	}
+}
@@ -0,0 +1,281 @@
+/*
+
+Package json reads and writes JSON documents backed by a flag.FlagSet, or any
+other FlagSet.
+
+Objects in the document form the path segments of dotted keys, so that
+
+	{"Foo": {"Port": 1234}, "Bar.Label": "hello"}
+
+sets the flags Foo.Port and Bar.Label.  Strings, numbers, and booleans are
+assigned to flags in their usual text form.  Arrays are assigned element by
+element, and so are only accepted by flags that hold several values, such as
+slices.  An array replaces the elements such a flag held before, where its
+value can remove them all, as those built by goflagbuilder.Into can.  Null
+values are skipped.
+
+Errors identify the offending node in the document by its JSON pointer.
+
+*/
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/suggest"
+)
+
+// Error describes a value in a JSON document that could not be assigned to
+// the flag for Key.  Pointer is the JSON pointer of the value.
+type Error struct {
+	Pointer string
+	Key     string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("key '%s' at %s: %v", e.Key, e.Pointer, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// UnknownKeyError describes a value in a JSON document whose key does not
+// match any flag.  Suggestions holds the names of similarly spelled flags, if
+// any.
+type UnknownKeyError struct {
+	Pointer     string
+	Key         string
+	Suggestions []string
+}
+
+func (e *UnknownKeyError) Error() string {
+	msg := fmt.Sprintf("unknown key '%s' at %s", e.Key, e.Pointer)
+	if s := suggest.Format(e.Suggestions); s != "" {
+		msg += ", " + s
+	}
+	return msg
+}
+
+// Option configures optional behavior of Parse and ParseFile.
+type Option func(*options)
+
+type options struct {
+	unknown func(*UnknownKeyError) error
+}
+
+// IgnoreUnknown skips values whose keys do not match any flag.
+func IgnoreUnknown() Option {
+	return OnUnknown(func(*UnknownKeyError) {})
+}
+
+// OnUnknown calls fn for each value whose key does not match any flag, and
+// then continues parsing.
+func OnUnknown(fn func(err *UnknownKeyError)) Option {
+	return func(o *options) {
+		o.unknown = func(err *UnknownKeyError) error {
+			fn(err)
+			return nil
+		}
+	}
+}
+
+var errNotObject = errors.New("document is not an object")
+
+type parser struct {
+	options
+	flagSet FlagSet
+	decoder *json.Decoder
+}
+
+// Parse reads a JSON document from the given Reader, setting values to
+// matching flags in the given flagset.  If flagSet is nil, then the global
+// flag.CommandLine FlagSet is used.
+func Parse(in io.Reader, flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	p := &parser{
+		options: options{
+			unknown: func(err *UnknownKeyError) error { return err },
+		},
+		flagSet: flagSet,
+		decoder: json.NewDecoder(in),
+	}
+	for _, opt := range opts {
+		opt(&p.options)
+	}
+	p.decoder.UseNumber()
+
+	token, err := p.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return errNotObject
+	}
+	if err := p.object("", ""); err != nil {
+		return err
+	}
+
+	if _, err := p.decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after document")
+	}
+	return nil
+}
+
+// ParseFile reads the JSON document in the file indicated by filename,
+// setting values to matching flags in the given flagset.  If flagSet is nil,
+// the global flag.CommandLine FlagSet is used.
+func ParseFile(filename string, flagSet FlagSet, opts ...Option) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return Parse(in, flagSet, opts...)
+}
+
+// object reads the members of an object whose opening brace has been read.
+func (p *parser) object(pointer, prefix string) error {
+	if prefix != "" {
+		prefix += "."
+	}
+
+	for p.decoder.More() {
+		token, err := p.decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		name := token.(string)
+		if err := p.value(pointer+"/"+escape(name), prefix+name); err != nil {
+			return err
+		}
+	}
+
+	_, err := p.decoder.Token()
+	return err
+}
+
+// value reads a single value and assigns it to the flag for key.
+func (p *parser) value(pointer, key string) error {
+	token, err := p.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	f := p.flagSet.Lookup(key)
+
+	switch token {
+	case json.Delim('{'):
+		if f != nil {
+			return &Error{pointer, key, errors.New("cannot assign an object")}
+		}
+		return p.object(pointer, key)
+
+	case json.Delim('['):
+		if f == nil {
+			return p.unknownArray(pointer, key)
+		}
+		return p.array(pointer, f)
+
+	case nil:
+		return nil
+	}
+
+	if f == nil {
+		return p.unknown(pointer, key)
+	}
+	if err := f.Value.Set(scalar(token)); err != nil {
+		return &Error{pointer, key, err}
+	}
+	return nil
+}
+
+// array reads the elements of an array whose opening bracket has been read,
+// and assigns each to f.
+func (p *parser) array(pointer string, f *flag.Flag) error {
+	if !flagutil.Multiple(f.Value) {
+		return &Error{pointer, f.Name, errors.New("cannot assign an array")}
+	}
+	if err := flagutil.Clear(f.Value); err != nil {
+		return &Error{pointer, f.Name, err}
+	}
+
+	for i := 0; p.decoder.More(); i++ {
+		elemPointer := fmt.Sprintf("%s/%d", pointer, i)
+
+		token, err := p.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if _, ok := token.(json.Delim); ok {
+			return &Error{elemPointer, f.Name, errors.New("cannot assign a nested array or object")}
+		}
+		if token == nil {
+			continue
+		}
+
+		if err := f.Value.Set(scalar(token)); err != nil {
+			return &Error{elemPointer, f.Name, err}
+		}
+	}
+
+	_, err := p.decoder.Token()
+	return err
+}
+
+func (p *parser) unknown(pointer, key string) error {
+	return p.options.unknown(&UnknownKeyError{
+		Pointer:     pointer,
+		Key:         key,
+		Suggestions: suggest.Closest(key, flagutil.Names(p.flagSet)),
+	})
+}
+
+// unknownArray reports an array without a flag, and skips over it.
+func (p *parser) unknownArray(pointer, key string) error {
+	if err := p.unknown(pointer, key); err != nil {
+		return err
+	}
+
+	for depth := 1; depth > 0; {
+		token, err := p.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('['), json.Delim('{'):
+			depth++
+		case json.Delim(']'), json.Delim('}'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// scalar formats a string, number, or boolean token as flag text.
+func scalar(token json.Token) string {
+	switch token := token.(type) {
+	case string:
+		return token
+	case json.Number:
+		return token.String()
+	}
+	return fmt.Sprint(token)
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escape(name string) string {
+	return pointerEscaper.Replace(name)
+}
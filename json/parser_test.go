@@ -0,0 +1,192 @@
+package json
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagtest"
+)
+
+func makeFlagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.String("Foo.Domain", "example.com", "")
+	flagSet.Int("Foo.Port", 9999, "")
+	flagSet.Bool("Foo.Secure", false, "")
+	flagSet.Float64("Bar.Nested.Index", 79.3, "")
+	flagSet.Duration("Timeout", 0, "")
+	flagSet.Var(&flagtest.IntSlice{}, "Ports", "")
+	return flagSet
+}
+
+func TestParseValid(t *testing.T) {
+	suite := []struct {
+		name  string
+		input string
+		end   map[string]string
+	}{
+		{
+			name:  "parse empty object",
+			input: "{}",
+			end: map[string]string{
+				"Foo.Domain": "example.com",
+				"Foo.Port":   "9999",
+			},
+		},
+		{
+			name:  "parse nested objects",
+			input: `{"Foo": {"Port": 1234, "Secure": true}, "Bar": {"Nested": {"Index": 7.9}}}`,
+			end: map[string]string{
+				"Foo.Domain":       "example.com",
+				"Foo.Port":         "1234",
+				"Foo.Secure":       "true",
+				"Bar.Nested.Index": "7.9",
+			},
+		},
+		{
+			name:  "parse dotted keys",
+			input: `{"Foo.Domain": "sushi.example", "Bar.Nested": {"Index": 1e3}}`,
+			end: map[string]string{
+				"Foo.Domain":       "sushi.example",
+				"Bar.Nested.Index": "1000",
+			},
+		},
+		{
+			name:  "parse arrays and nulls",
+			input: `{"Ports": [80, null, 443], "Timeout": "3s", "Foo": {"Port": null}}`,
+			end: map[string]string{
+				"Ports":    "[80, 443]",
+				"Timeout":  "3s",
+				"Foo.Port": "9999",
+			},
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			flagSet := makeFlagSet(item.name)
+
+			// In order to test default FlagSet
+			commandLine := flag.CommandLine
+			flag.CommandLine = flagSet
+			defer func() { flag.CommandLine = commandLine }()
+
+			if err := Parse(strings.NewReader(item.input), nil); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+
+			for k, v := range item.end {
+				if actual := flagSet.Lookup(k).Value.String(); v != actual {
+					t.Errorf("values for %s not equal\nexpected: %v\n  actual: %v", k, v, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	suite := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "parse array document",
+			input: `[1, 2]`,
+			err:   "document is not an object",
+		},
+		{
+			name:  "parse unknown key",
+			input: `{"Foo": {"Prot": 10}}`,
+			err:   "unknown key 'Foo.Prot' at /Foo/Prot, did you mean 'Foo.Port'?",
+		},
+		{
+			name:  "parse unknown array",
+			input: `{"Other": [1]}`,
+			err:   "unknown key 'Other' at /Other",
+		},
+		{
+			name:  "parse escaped pointer",
+			input: `{"a/b~c": 1}`,
+			err:   "unknown key 'a/b~c' at /a~1b~0c",
+		},
+		{
+			name:  "parse type mismatch",
+			input: `{"Foo": {"Secure": "maybe"}}`,
+			err:   `key 'Foo.Secure' at /Foo/Secure: parse error`,
+		},
+		{
+			name:  "parse object for value",
+			input: `{"Foo": {"Port": {"Number": 1}}}`,
+			err:   "key 'Foo.Port' at /Foo/Port: cannot assign an object",
+		},
+		{
+			name:  "parse array for value",
+			input: `{"Foo": {"Port": [1]}}`,
+			err:   "key 'Foo.Port' at /Foo/Port: cannot assign an array",
+		},
+		{
+			name:  "parse bad array element",
+			input: `{"Ports": [80, "http"]}`,
+			err:   `key 'Ports' at /Ports/1: strconv.Atoi: parsing "http": invalid syntax`,
+		},
+		{
+			name:  "parse nested array",
+			input: `{"Ports": [[80]]}`,
+			err:   "key 'Ports' at /Ports/0: cannot assign a nested array or object",
+		},
+		{
+			name:  "parse trailing data",
+			input: `{} {}`,
+			err:   "unexpected data after document",
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			err := Parse(strings.NewReader(item.input), makeFlagSet(item.name))
+			if err == nil {
+				t.Error("expected error:", item.err)
+			} else if item.err != err.Error() {
+				t.Errorf("errors don't match\nexpected: %s\n  actual: %s", item.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseErrorUnwrap(t *testing.T) {
+	err := Parse(strings.NewReader(`{"Foo": {"Port": "http"}}`), makeFlagSet("unwrap"))
+
+	var jsonErr *Error
+	if !errors.As(err, &jsonErr) {
+		t.Fatal("expected *Error, got", err)
+	}
+	if jsonErr.Pointer != "/Foo/Port" || jsonErr.Key != "Foo.Port" || jsonErr.Err == nil {
+		t.Errorf("unexpected error fields: %#v", jsonErr)
+	}
+}
+
+func TestParseUnknown(t *testing.T) {
+	flagSet := makeFlagSet("unknown")
+	input := `{"Other": {"Deep": [1, {"x": 2}]}, "Foo": {"Prot": 1, "Port": 2}}`
+
+	var unknown []string
+	err := Parse(strings.NewReader(input), flagSet, OnUnknown(func(err *UnknownKeyError) {
+		unknown = append(unknown, err.Pointer)
+	}))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if strings.Join(unknown, ",") != "/Other/Deep,/Foo/Prot" {
+		t.Error("unexpected unknown keys:", unknown)
+	}
+	if v := flagSet.Lookup("Foo.Port").Value.String(); v != "2" {
+		t.Error("parsing did not continue past unknown keys, got", v)
+	}
+
+	if err := Parse(strings.NewReader(input), makeFlagSet("ignore"), IgnoreUnknown()); err != nil {
+		t.Error("unexpected error:", err)
+	}
+}
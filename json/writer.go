@@ -0,0 +1,120 @@
+package json
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+)
+
+// Write emits the current value of every flag in the given flagset as a JSON
+// document that Parse will read back.  Dotted keys are written as nested
+// objects.  Values are written with their native JSON types where the flag
+// value is a flag.Getter of a basic type or slice, and as strings otherwise.
+// If flagSet is nil, the global flag.CommandLine FlagSet is used.
+func Write(out io.Writer, flagSet FlagSet) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	doc := make(map[string]interface{})
+	var err error
+
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+
+		node := doc
+		segments := strings.Split(f.Name, ".")
+		for i, segment := range segments[:len(segments)-1] {
+			child, ok := node[segment].(map[string]interface{})
+			if !ok {
+				if _, exists := node[segment]; exists {
+					err = fmt.Errorf("key '%s' conflicts with key '%s'", f.Name, strings.Join(segments[:i+1], "."))
+					return
+				}
+				child = make(map[string]interface{})
+				node[segment] = child
+			}
+			node = child
+		}
+
+		last := segments[len(segments)-1]
+		if _, exists := node[last]; exists {
+			err = fmt.Errorf("key '%s' conflicts with a nested key", f.Name)
+			return
+		}
+		node[last] = native(f.Value)
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(doc)
+}
+
+// native returns the value of v as a basic Go type if possible, and otherwise
+// as its string form.
+func native(v flag.Value) interface{} {
+	getter, ok := v.(flag.Getter)
+	if !ok {
+		return v.String()
+	}
+
+	value := getter.Get()
+	r := reflect.ValueOf(value)
+	switch {
+	case !r.IsValid():
+		return v.String()
+
+	case r.Kind() == reflect.Slice && basic(r.Type().Elem()):
+		items := make([]interface{}, r.Len())
+		for i := range items {
+			items[i] = finite(r.Index(i))
+		}
+		return items
+
+	case basic(r.Type()):
+		return finite(r)
+	}
+
+	return v.String()
+}
+
+// finite returns the value of r, or its string form if it is an infinite or
+// NaN float, which JSON cannot represent as a number.
+func finite(r reflect.Value) interface{} {
+	if k := r.Kind(); k == reflect.Float32 || k == reflect.Float64 {
+		if f := r.Float(); math.IsInf(f, 0) || math.IsNaN(f) {
+			return strconv.FormatFloat(f, 'g', -1, r.Type().Bits())
+		}
+	}
+	return r.Interface()
+}
+
+// basic reports whether t is one of the predeclared boolean, numeric, or
+// string types.  Named types such as time.Duration may not be written in
+// the same form that their Set method reads, and so are excluded.
+func basic(t reflect.Type) bool {
+	if t.PkgPath() != "" {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package json
+
+import (
+	"bytes"
+	"flag"
+	"math"
+	"testing"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagtest"
+)
+
+func TestWrite(t *testing.T) {
+	flagSet := makeFlagSet("write")
+	flagSet.Var(&flagtest.IntSlice{}, "Empty", "")
+	args := []string{"-Foo.Port", "1234", "-Ports", "80", "-Ports", "443", "-Timeout", "1m30s"}
+	if err := flagSet.Parse(args); err != nil {
+		t.Fatal("error parsing args:", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, flagSet); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := `{
+	"Bar": {
+		"Nested": {
+			"Index": 79.3
+		}
+	},
+	"Empty": [],
+	"Foo": {
+		"Domain": "example.com",
+		"Port": 1234,
+		"Secure": false
+	},
+	"Ports": [
+		80,
+		443
+	],
+	"Timeout": "1m30s"
+}
+`
+	if buf.String() != expected {
+		t.Errorf("output not equal\nexpected: %s\n  actual: %s", expected, buf.String())
+	}
+
+	readSet := makeFlagSet("read")
+	readSet.Var(&flagtest.IntSlice{}, "Empty", "")
+	if err := Parse(&buf, readSet); err != nil {
+		t.Fatal("error parsing output:", err)
+	}
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if actual := readSet.Lookup(f.Name).Value.String(); actual != f.Value.String() {
+			t.Errorf("values for %s not equal\nexpected: %s\n  actual: %s", f.Name, f.Value.String(), actual)
+		}
+	})
+}
+
+func TestWriteConflict(t *testing.T) {
+	flagSet := flag.NewFlagSet("conflict", flag.ContinueOnError)
+	flagSet.Int("Foo", 1, "")
+	flagSet.Int("Foo.Port", 2, "")
+
+	var buf bytes.Buffer
+	err := Write(&buf, flagSet)
+	if err == nil || err.Error() != "key 'Foo.Port' conflicts with key 'Foo'" {
+		t.Error("unexpected error:", err)
+	}
+}
+
+func TestWriteNonFinite(t *testing.T) {
+	flagSet := flag.NewFlagSet("nonfinite", flag.ContinueOnError)
+	flagSet.Float64("Inf", math.Inf(1), "")
+	flagSet.Float64("NegInf", math.Inf(-1), "")
+	flagSet.Float64("NaN", math.NaN(), "")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, flagSet); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := `{
+	"Inf": "+Inf",
+	"NaN": "NaN",
+	"NegInf": "-Inf"
+}
+`
+	if buf.String() != expected {
+		t.Errorf("output not equal\nexpected: %s\n  actual: %s", expected, buf.String())
+	}
+
+	readSet := flag.NewFlagSet("read", flag.ContinueOnError)
+	readSet.Float64("Inf", 0, "")
+	readSet.Float64("NegInf", 0, "")
+	readSet.Float64("NaN", 0, "")
+	if err := Parse(&buf, readSet); err != nil {
+		t.Fatal("error parsing output:", err)
+	}
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if actual := readSet.Lookup(f.Name).Value.String(); actual != f.Value.String() {
+			t.Errorf("values for %s not equal\nexpected: %s\n  actual: %s", f.Name, f.Value.String(), actual)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+/*
+
+Package keydir reads a directory holding one file per key into values backed
+by a flag.FlagSet, or any other FlagSet.  This is how Kubernetes mounts
+ConfigMaps and Secrets as volumes, and how Docker provides secrets.
+
+Each file is named by its key, and its content is the value.  Subdirectories
+form path segments of dotted keys, so that
+
+	config/Foo.Port
+	config/Bar/Label
+
+set the flags Foo.Port and Bar.Label.  One trailing newline is trimmed from
+each value by default.
+
+Files and directories whose names start with "." are skipped, as are the
+"..data" link and timestamped directories that Kubernetes maintains beside the
+keys.  If the directory has a "..data" link, it is resolved once and read in
+its place, so that a volume updated while it is read still yields a consistent
+set of values.  The NoDataLink option reads the directory itself instead.
+
+*/
+package keydir
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/suggest"
+)
+
+// dataLink is the link through which Kubernetes atomically updates a volume.
+const dataLink = "..data"
+
+// Error describes a file whose content could not be assigned to the flag for
+// Key.
+type Error struct {
+	Path string
+	Key  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("key '%s' in %s: %v", e.Key, e.Path, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// UnknownKeyError describes a file whose key does not match any flag.
+// Suggestions holds the names of similarly spelled flags, if any.
+type UnknownKeyError struct {
+	Path        string
+	Key         string
+	Suggestions []string
+}
+
+func (e *UnknownKeyError) Error() string {
+	msg := fmt.Sprintf("unknown key '%s' in %s", e.Key, e.Path)
+	if s := suggest.Format(e.Suggestions); s != "" {
+		msg += ", " + s
+	}
+	return msg
+}
+
+// Option configures optional behavior of Parse.
+type Option func(*options)
+
+type options struct {
+	unknown    func(*UnknownKeyError) error
+	trim       func(string) string
+	hidden     bool
+	noDataLink bool
+}
+
+// IgnoreUnknown skips files whose keys do not match any flag.
+func IgnoreUnknown() Option {
+	return OnUnknown(func(*UnknownKeyError) {})
+}
+
+// OnUnknown calls fn for each file whose key does not match any flag, and then
+// continues parsing.
+func OnUnknown(fn func(err *UnknownKeyError)) Option {
+	return func(o *options) {
+		o.unknown = func(err *UnknownKeyError) error {
+			fn(err)
+			return nil
+		}
+	}
+}
+
+// NoTrim assigns the content of each file exactly, without trimming a trailing
+// newline.
+func NoTrim() Option {
+	return func(o *options) { o.trim = func(s string) string { return s } }
+}
+
+// TrimSpace trims all leading and trailing white space from the content of each
+// file.
+func TrimSpace() Option {
+	return func(o *options) { o.trim = strings.TrimSpace }
+}
+
+// IncludeHidden reads files and directories whose names start with a single
+// ".", which are otherwise skipped.  Names starting with "..", as used by
+// Kubernetes for its own bookkeeping, are always skipped.
+func IncludeHidden() Option {
+	return func(o *options) { o.hidden = true }
+}
+
+// NoDataLink reads dir itself rather than the target of its "..data" link,
+// following the link of each key instead.  Paths in errors then name the files
+// in dir, but a volume updated while it is read may yield a mix of old and new
+// values.
+func NoDataLink() Option {
+	return func(o *options) { o.noDataLink = true }
+}
+
+// Parse reads each file in the directory dir, setting its content to the flag
+// named by its path relative to dir, in lexical order.  If flagSet is nil, then
+// the global flag.CommandLine FlagSet is used.
+func Parse(dir string, flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	o := options{
+		unknown: func(err *UnknownKeyError) error { return err },
+		trim:    trimNewline,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.noDataLink {
+		return o.parseDir(flagSet, dir, "")
+	}
+
+	if data, err := filepath.EvalSymlinks(filepath.Join(dir, dataLink)); err == nil {
+		dir = data
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return o.parseDir(flagSet, dir, "")
+}
+
+// parseDir reads the files in dir, whose keys start with prefix.
+func (o *options) parseDir(flagSet FlagSet, dir string, prefix string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if strings.HasPrefix(name, "..") || strings.HasPrefix(name, ".") && !o.hidden {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+
+		// Stat follows the links that Kubernetes makes for each key.
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if err := o.parseDir(flagSet, path, prefix+name+"."); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := o.parseFile(flagSet, path, prefix+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseFile assigns the content of the file at path to the flag for key.
+func (o *options) parseFile(flagSet FlagSet, path string, key string) error {
+	f := flagSet.Lookup(key)
+	if f == nil {
+		return o.unknown(&UnknownKeyError{
+			Path:        path,
+			Key:         key,
+			Suggestions: suggest.Closest(key, flagutil.Names(flagSet)),
+		})
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Value.Set(o.trim(string(data))); err != nil {
+		return &Error{Path: path, Key: key, Err: err}
+	}
+	return nil
+}
+
+// trimNewline removes one trailing newline from s.
+func trimNewline(s string) string {
+	if strings.HasSuffix(s, "\r\n") {
+		return s[:len(s)-2]
+	}
+	return strings.TrimSuffix(s, "\n")
+}
@@ -0,0 +1,186 @@
+package keydir
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTree creates files under dir with the given contents, creating parent
+// directories as needed.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal("failed to create directory:", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal("failed to write file:", err)
+		}
+	}
+}
+
+func makeFlagSet() (*flag.FlagSet, *int, *string, *string) {
+	flagSet := flag.NewFlagSet("keydir", flag.ContinueOnError)
+	port := flagSet.Int("Foo.Port", 0, "")
+	label := flagSet.String("Bar.Label", "", "")
+	hidden := flagSet.String(".hidden", "", "")
+	return flagSet, port, label, hidden
+}
+
+func TestParse(t *testing.T) {
+	suite := []struct {
+		name   string
+		files  map[string]string
+		opts   []Option
+		port   int
+		label  string
+		hidden string
+	}{
+		{
+			name:  "dotted",
+			files: map[string]string{"Foo.Port": "1234\n", "Bar.Label": "hello\n\n"},
+			port:  1234,
+			label: "hello\n",
+		},
+		{
+			name:  "nested",
+			files: map[string]string{"Foo/Port": "1234", "Bar/Label": "hello\r\n"},
+			port:  1234,
+			label: "hello",
+		},
+		{
+			name:  "no trim",
+			files: map[string]string{"Bar.Label": "hello\n"},
+			opts:  []Option{NoTrim()},
+			label: "hello\n",
+		},
+		{
+			name:  "trim space",
+			files: map[string]string{"Foo.Port": " 1234 \n", "Bar.Label": "\thello\n\n"},
+			opts:  []Option{TrimSpace()},
+			port:  1234,
+			label: "hello",
+		},
+		{
+			name:  "hidden skipped",
+			files: map[string]string{".hidden": "x", ".git/config": "x", "Bar.Label": "hello"},
+			label: "hello",
+		},
+		{
+			name:   "hidden included",
+			files:  map[string]string{".hidden": "x", "..2020_01_01/Bar.Label": "x"},
+			opts:   []Option{IncludeHidden()},
+			hidden: "x",
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "goflagbuilder-test")
+			if err != nil {
+				t.Fatal("failed to create temp dir:", err)
+			}
+			defer os.RemoveAll(dir)
+			writeTree(t, dir, item.files)
+
+			flagSet, port, label, hidden := makeFlagSet()
+			if err := Parse(dir, flagSet, item.opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if *port != item.port || *label != item.label || *hidden != item.hidden {
+				t.Errorf("unexpected values %d %q %q", *port, *label, *hidden)
+			}
+		})
+	}
+}
+
+func TestParseKubernetesVolume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	if err != nil {
+		t.Fatal("failed to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTree(t, dir, map[string]string{
+		"..2020_01_01/Foo.Port":  "1",
+		"..2020_01_02/Foo.Port":  "2",
+		"..2020_01_02/Bar/Label": "current",
+		"..2020_01_02/Baz":       "new",
+	})
+	links := map[string]string{
+		"..data":   "..2020_01_02",
+		"Foo.Port": "..data/Foo.Port",
+		"Bar":      "..data/Bar",
+		"Baz":      "..data/Baz",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal("failed to create link:", err)
+		}
+	}
+
+	suite := []struct {
+		name    string
+		opts    []Option
+		unknown string
+	}{
+		{"data link", nil, filepath.Join(dir, "..2020_01_02", "Baz")},
+		{"no data link", []Option{NoDataLink()}, filepath.Join(dir, "Baz")},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			var unknown []string
+			opts := append(item.opts, OnUnknown(func(err *UnknownKeyError) {
+				unknown = append(unknown, err.Path)
+			}))
+
+			flagSet, port, label, _ := makeFlagSet()
+			if err := Parse(dir, flagSet, opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if *port != 2 || *label != "current" {
+				t.Errorf("unexpected values %d %q", *port, *label)
+			}
+			if len(unknown) != 1 || unknown[0] != item.unknown {
+				t.Error("unexpected unknown keys:", unknown)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	if err != nil {
+		t.Fatal("failed to create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	writeTree(t, dir, map[string]string{"Foo.Porr": "1", "Foo.Port": "x"})
+
+	flagSet, _, _, _ := makeFlagSet()
+	err = Parse(dir, flagSet)
+	expected := "unknown key 'Foo.Porr' in " + filepath.Join(dir, "Foo.Porr") + ", did you mean 'Foo.Port'?"
+	if err == nil || err.Error() != expected {
+		t.Error("unexpected error:", err)
+	}
+
+	var unknown []string
+	err = Parse(dir, flagSet, OnUnknown(func(err *UnknownKeyError) {
+		unknown = append(unknown, err.Key)
+	}))
+	var keyErr *Error
+	if !errors.As(err, &keyErr) || keyErr.Key != "Foo.Port" {
+		t.Error("unexpected error:", err)
+	}
+	if len(unknown) != 1 || unknown[0] != "Foo.Porr" {
+		t.Error("unexpected unknown keys:", unknown)
+	}
+
+	if err := Parse(filepath.Join(dir, "missing"), flagSet); !os.IsNotExist(err) {
+		t.Error("unexpected error:", err)
+	}
+}
@@ -0,0 +1,411 @@
+package goflagbuilder
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/env"
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+	"github.com/BellerophonMobile/goflagbuilder/v2/keydir"
+)
+
+// Origins reported by Loader for values that do not come from a file.
+const (
+	OriginDefault = "default"
+	OriginEnv     = "env"
+	OriginFlags   = "flags"
+)
+
+// A Source is a layer of configuration applied by a Loader.  It should set
+// values through the Flags returned by the Loader's Flags method, so that
+// the Loader can tell which layer set each key.
+type Source interface {
+	Apply(l *Loader) error
+}
+
+// SourceFunc is a Source implemented by a function.
+type SourceFunc func(l *Loader) error
+
+// Apply calls f(l).
+func (f SourceFunc) Apply(l *Loader) error { return f(l) }
+
+// flagDefiner is implemented by sources with flags of their own, which
+// NewLoader defines on the loader's flag set so that the command line may
+// give them.
+type flagDefiner interface {
+	defineFlags(flagSet *flag.FlagSet)
+}
+
+// Loader applies layers of configuration to a flag set in a fixed order of
+// precedence: defaults, then each Source in the order given, then the command
+// line.  The usual sources are files followed by the environment, so that
+// environment variables override files and command line flags override both.
+//
+// The command line is parsed before any source is applied, so that sources
+// may depend on it, such as to find a configuration file named by a flag.
+// Its values are nonetheless assigned last, so that they take precedence.
+type Loader struct {
+	flagSet *flag.FlagSet
+	sources []Source
+
+	args    []string
+	cli     []setting
+	origins map[string]string
+	files   []string
+	profile string
+}
+
+type setting struct {
+	name  string
+	value string
+}
+
+// NewLoader returns a Loader that applies the given sources, followed by the
+// command line, to flagSet.  If flagSet is nil, the global flag.CommandLine
+// FlagSet is used.  Flags needed by the sources, such as the -profile flag of
+// Profile, are defined on flagSet unless it already has them.
+func NewLoader(flagSet *flag.FlagSet, sources ...Source) *Loader {
+	if flagSet == nil {
+		flagSet = flag.CommandLine
+	}
+
+	for _, source := range sources {
+		if definer, ok := source.(flagDefiner); ok {
+			definer.defineFlags(flagSet)
+		}
+	}
+
+	return &Loader{
+		flagSet: flagSet,
+		sources: sources,
+	}
+}
+
+// Load parses the command line arguments args, applies each source, and then
+// applies the flags from args.  Arguments remaining after the flags are
+// available from the flag set's Args method as usual.  Unlike
+// flag.FlagSet.Parse, errors are always returned rather than handled
+// according to the flag set's ErrorHandling.
+func (l *Loader) Load(args []string) error {
+	l.args = args
+	l.cli = nil
+	l.origins = make(map[string]string)
+	l.files = nil
+	l.profile = ""
+
+	scratch := flag.NewFlagSet(l.flagSet.Name(), flag.ContinueOnError)
+	scratch.SetOutput(l.flagSet.Output())
+	scratch.Usage = func() {
+		if l.flagSet.Usage != nil {
+			l.flagSet.Usage()
+		} else {
+			l.flagSet.PrintDefaults()
+		}
+	}
+
+	l.flagSet.VisitAll(func(f *flag.Flag) {
+		scratch.Var(&capture{l, f.Name, flagutil.IsBool(f.Value)}, f.Name, f.Usage)
+	})
+	if err := scratch.Parse(args); err != nil {
+		return err
+	}
+
+	for _, source := range l.sources {
+		if err := source.Apply(l); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range l.cli {
+		if err := l.flagSet.Set(s.name, s.value); err != nil {
+			return fmt.Errorf("invalid value %q for flag -%s: %w", s.value, s.name, err)
+		}
+		l.origins[s.name] = OriginFlags
+	}
+
+	return l.flagSet.Parse(append([]string{"--"}, scratch.Args()...))
+}
+
+// Origin returns the layer that last set key in the most recent Load: the name
+// of a file, OriginEnv, OriginFlags, or OriginDefault if no layer set it.
+func (l *Loader) Origin(key string) string {
+	if origin, ok := l.origins[key]; ok {
+		return origin
+	}
+	return OriginDefault
+}
+
+// Origins returns the layer that last set each key that was set in the most
+// recent Load.
+func (l *Loader) Origins() map[string]string {
+	origins := make(map[string]string, len(l.origins))
+	for k, v := range l.origins {
+		origins[k] = v
+	}
+	return origins
+}
+
+// Files returns the configuration files, and directories read by Directory,
+// read by the sources in the most recent Load, in the order they were applied.
+func (l *Loader) Files() []string {
+	return append([]string(nil), l.files...)
+}
+
+// CommandLine returns each value given for the named flag on the command
+// line, in order.  Sources may use it to find files named by flags, which are
+// not yet set when the sources are applied.
+func (l *Loader) CommandLine(name string) []string {
+	var values []string
+	for _, s := range l.cli {
+		if s.name == name {
+			values = append(values, s.value)
+		}
+	}
+	return values
+}
+
+// Flags returns the loader's flag set, recording origin as the origin of each
+// value set through it.
+func (l *Loader) Flags(origin string) Flags {
+	return recorder{l, origin}
+}
+
+// ParseFile reads the configuration file indicated by filename with ParseFile
+// and the given options, recording the file as the origin of the values it
+// sets and adding it to Files.
+//
+// If a profile has been selected, its overrides are then applied: first any
+// profile sections of the file, recorded with an origin such as
+// "app.conf [profile.prod]", and then any sibling file named for the profile,
+// such as "app.prod.toml" beside "app.toml".
+func (l *Loader) ParseFile(filename string, opts ...ParseOption) error {
+	if err := ParseFile(filename, l.Flags(filename), opts...); err != nil {
+		return err
+	}
+	l.files = append(l.files, filename)
+
+	if l.profile == "" {
+		return nil
+	}
+
+	origin := fmt.Sprintf("%s [profile.%s]", filename, l.profile)
+	if err := ParseProfile(filename, l.Flags(origin), l.profile, opts...); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(filename)
+	overlay := strings.TrimSuffix(filename, ext) + "." + l.profile + ext
+	if err := ParseFile(overlay, l.Flags(overlay), opts...); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	l.files = append(l.files, overlay)
+	return nil
+}
+
+// Profile returns the profile selected by the Profile source in the most
+// recent Load, or "" if there is none.
+func (l *Loader) Profile() string {
+	return l.profile
+}
+
+// File returns a Source that reads the configuration file indicated by
+// filename with ParseFile and the given options.
+func File(filename string, opts ...ParseOption) Source {
+	return SourceFunc(func(l *Loader) error {
+		return l.ParseFile(filename, opts...)
+	})
+}
+
+// FileFlag returns a Source that reads each configuration file named by the
+// given flag on the command line, in order, with the given options.  The flag
+// must be defined on the loader's flag set.
+func FileFlag(name string, opts ...ParseOption) Source {
+	return SourceFunc(func(l *Loader) error {
+		for _, filename := range l.CommandLine(name) {
+			if err := l.ParseFile(filename, opts...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Directory returns a Source that reads a directory of files, one per key,
+// with keydir.Parse and the given options, recording dir as their origin.
+func Directory(dir string, opts ...keydir.Option) Source {
+	return SourceFunc(func(l *Loader) error {
+		if err := keydir.Parse(dir, l.Flags(dir), opts...); err != nil {
+			return err
+		}
+		l.files = append(l.files, dir)
+		return nil
+	})
+}
+
+// ProfileFlag is the name of the flag registered by Profile.
+const ProfileFlag = "profile"
+
+// Profile returns a Source that selects the profile named by a -profile flag,
+// such as "dev" or "prod", which NewLoader defines on the loader's flag set.
+// If the flag is not given, the profile is taken from the environment variable
+// envVar, if that is not "".  The files read by later sources are then
+// overlaid with the profile's overrides, as described by Loader.ParseFile, so
+// the Source must come before them.
+func Profile(envVar string) Source {
+	return profileSource{envVar}
+}
+
+type profileSource struct {
+	envVar string
+}
+
+func (p profileSource) defineFlags(flagSet *flag.FlagSet) {
+	if flagSet.Lookup(ProfileFlag) == nil {
+		flagSet.String(ProfileFlag, "", "Configuration profile to apply")
+	}
+}
+
+func (p profileSource) Apply(l *Loader) error {
+	if values := l.CommandLine(ProfileFlag); len(values) > 0 {
+		l.profile = values[len(values)-1]
+		return nil
+	}
+
+	if p.envVar == "" {
+		return nil
+	}
+	value, ok := os.LookupEnv(p.envVar)
+	if !ok {
+		return nil
+	}
+	l.profile = value
+	return l.Flags(OriginEnv).Lookup(ProfileFlag).Value.Set(value)
+}
+
+// Env returns a Source that reads environment variables with env.Parse and
+// the given options.  It first fails if env.Check finds flags whose variables
+// share a name.
+func Env(opts ...env.Option) Source {
+	return SourceFunc(func(l *Loader) error {
+		flags := l.Flags(OriginEnv)
+		if err := env.Check(flags, opts...); err != nil {
+			return err
+		}
+		return env.Parse(flags, opts...)
+	})
+}
+
+// capture stands in for a flag while the command line is first parsed,
+// recording its values for later.
+type capture struct {
+	loader *Loader
+	name   string
+	isBool bool
+}
+
+func (c *capture) Set(s string) error {
+	c.loader.cli = append(c.loader.cli, setting{c.name, s})
+	return nil
+}
+
+func (c *capture) String() string   { return "" }
+func (c *capture) IsBoolFlag() bool { return c.isBool }
+
+// recorder is a view of a loader's flag set whose values record their origin
+// when set.
+type recorder struct {
+	loader *Loader
+	origin string
+}
+
+func (r recorder) Name() string { return r.loader.flagSet.Name() }
+
+func (r recorder) Lookup(name string) *flag.Flag {
+	f := r.loader.flagSet.Lookup(name)
+	if f == nil {
+		return nil
+	}
+	return r.wrap(f)
+}
+
+func (r recorder) VisitAll(fn func(*flag.Flag)) {
+	r.loader.flagSet.VisitAll(func(f *flag.Flag) {
+		fn(r.wrap(f))
+	})
+}
+
+func (r recorder) wrap(f *flag.Flag) *flag.Flag {
+	var value flag.Value = &recordValue{r, f}
+	if _, ok := f.Value.(flag.Getter); ok {
+		value = &recordGetter{recordValue{r, f}}
+	}
+
+	return &flag.Flag{
+		Name:     f.Name,
+		Usage:    f.Usage,
+		Value:    value,
+		DefValue: f.DefValue,
+	}
+}
+
+// recordValue wraps a flag's value, recording its origin when it is changed.
+// Optional methods of the value are passed through, falling back to the same
+// behavior as if they were absent.
+type recordValue struct {
+	recorder recorder
+	flag     *flag.Flag
+}
+
+func (v *recordValue) record(err error) error {
+	if err == nil {
+		v.recorder.loader.origins[v.flag.Name] = v.recorder.origin
+	}
+	return err
+}
+
+func (v *recordValue) Set(s string) error { return v.record(v.flag.Value.Set(s)) }
+func (v *recordValue) String() string     { return v.flag.Value.String() }
+func (v *recordValue) IsBoolFlag() bool   { return flagutil.IsBool(v.flag.Value) }
+
+func (v *recordValue) Remove(s string) error {
+	remover, ok := v.flag.Value.(interface{ Remove(string) error })
+	if !ok {
+		return fmt.Errorf("value of %s does not hold multiple elements", v.flag.Name)
+	}
+	return v.record(remover.Remove(s))
+}
+
+func (v *recordValue) Clear() error {
+	clearer, ok := v.flag.Value.(interface{ Clear() error })
+	if !ok {
+		return fmt.Errorf("value of %s does not hold multiple elements", v.flag.Name)
+	}
+	return v.record(clearer.Clear())
+}
+
+func (v *recordValue) Reset() error {
+	if resetter, ok := v.flag.Value.(interface{ Reset() error }); ok {
+		return v.record(resetter.Reset())
+	}
+	return v.record(v.flag.Value.Set(v.flag.DefValue))
+}
+
+func (v *recordValue) EnvNames() []string {
+	if namer, ok := v.flag.Value.(interface{ EnvNames() []string }); ok {
+		return namer.EnvNames()
+	}
+	return nil
+}
+
+// recordGetter is a recordValue for a flag.Getter.
+type recordGetter struct {
+	recordValue
+}
+
+func (v *recordGetter) Get() interface{} { return v.flag.Value.(flag.Getter).Get() }
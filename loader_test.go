@@ -0,0 +1,170 @@
+package goflagbuilder
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type loadercomponent struct {
+	Domain  string
+	Port    int
+	Label   string
+	Verbose bool
+}
+
+func TestLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "base.conf")
+	require.NoError(t, ioutil.WriteFile(base, []byte("Domain = base.example\nPort = 1\nLabel = base"), 0644))
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.Mkdir(secrets, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(secrets, "Domain"), []byte("secret.example\n"), 0644))
+	extra := filepath.Join(dir, "extra.json")
+	require.NoError(t, ioutil.WriteFile(extra, []byte(`{"Port": 2, "Label": "extra"}`), 0644))
+
+	os.Setenv("LOADERTEST_PORT", "3")
+	defer os.Unsetenv("LOADERTEST_PORT")
+
+	conf := &loadercomponent{Domain: "default.example"}
+	flagSet := flag.NewFlagSet("loadertest", flag.ContinueOnError)
+	var config string
+	flagSet.StringVar(&config, "config", "", "Configuration file")
+	require.NoError(t, Into(flagSet, conf))
+
+	loader := NewLoader(flagSet, File(base), FileFlag("config"), Directory(secrets), Env())
+	require.NoError(t, loader.Load([]string{"-Label", "flags", "-Verbose", "-config", extra, "rest"}))
+
+	assert.Equal(t, "secret.example", conf.Domain)
+	assert.Equal(t, 3, conf.Port)
+	assert.Equal(t, "flags", conf.Label)
+	assert.True(t, conf.Verbose)
+	assert.Equal(t, extra, config)
+	assert.Equal(t, []string{"rest"}, flagSet.Args())
+
+	assert.Equal(t, secrets, loader.Origin("Domain"))
+	assert.Equal(t, OriginEnv, loader.Origin("Port"))
+	assert.Equal(t, OriginFlags, loader.Origin("Label"))
+	assert.Equal(t, OriginFlags, loader.Origin("Verbose"))
+	assert.Equal(t, OriginDefault, loader.Origin("Missing"))
+	assert.Equal(t, []string{extra}, loader.CommandLine("config"))
+	assert.Len(t, loader.Origins(), 5)
+}
+
+func TestLoaderErrors(t *testing.T) {
+	suite := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{"unknown flag", []string{"-Prot", "1"}, "flag provided but not defined: -Prot"},
+		{"bad flag value", []string{"-Port", "x"}, `invalid value "x" for flag -Port: strconv.ParseInt: parsing "x": invalid syntax`},
+		{"missing file", []string{"-config", "missing.conf"}, "open missing.conf: no such file or directory"},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet("loadertest", flag.ContinueOnError)
+			flagSet.SetOutput(ioutil.Discard)
+			flagSet.String("config", "", "Configuration file")
+			require.NoError(t, Into(flagSet, &loadercomponent{}))
+
+			err := NewLoader(flagSet, FileFlag("config")).Load(item.args)
+			assert.EqualError(t, err, item.err)
+		})
+	}
+}
+
+func TestLoaderIgnoreUnknown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.conf")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("Port = 1\nNewFeature = on\n[profile.prod]\nNewer = on"), 0644))
+
+	conf := &loadercomponent{}
+	flagSet := flag.NewFlagSet("loadertest", flag.ContinueOnError)
+	require.NoError(t, Into(flagSet, conf))
+
+	profile := Profile("")
+	require.NoError(t, NewLoader(flagSet, profile, File(filename, IgnoreUnknown())).Load([]string{"-profile", "prod"}))
+	assert.Equal(t, 1, conf.Port)
+
+	err = NewLoader(flagSet, profile, File(filename)).Load(nil)
+	assert.EqualError(t, err, filename+": unknown key 'NewFeature' on line 2")
+}
+
+func TestLoaderProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "app.conf")
+	overlay := filepath.Join(dir, "app.prod.conf")
+	require.NoError(t, ioutil.WriteFile(base, []byte("Domain = base.example\nPort = 1\n[profile.prod]\nPort = 2\n[profile.dev]\nPort = 3"), 0644))
+	require.NoError(t, ioutil.WriteFile(overlay, []byte("Label = overlay"), 0644))
+
+	os.Setenv("LOADERTEST_PROFILE", "dev")
+	defer os.Unsetenv("LOADERTEST_PROFILE")
+
+	suite := []struct {
+		name    string
+		args    []string
+		profile string
+		port    int
+		label   string
+		files   []string
+	}{
+		{"flag", []string{"-profile", "prod"}, "prod", 2, "overlay", []string{base, overlay}},
+		{"env", nil, "dev", 3, "", []string{base}},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			conf := &loadercomponent{}
+			flagSet := flag.NewFlagSet("loadertest", flag.ContinueOnError)
+			require.NoError(t, Into(flagSet, conf))
+
+			loader := NewLoader(flagSet, Profile("LOADERTEST_PROFILE"), File(base))
+			require.NoError(t, loader.Load(item.args))
+
+			assert.Equal(t, item.profile, loader.Profile())
+			assert.Equal(t, item.profile, flagSet.Lookup(ProfileFlag).Value.String())
+			assert.Equal(t, "base.example", conf.Domain)
+			assert.Equal(t, item.port, conf.Port)
+			assert.Equal(t, item.label, conf.Label)
+			assert.Equal(t, item.files, loader.Files())
+			assert.Equal(t, base, loader.Origin("Domain"))
+			assert.Equal(t, base+" [profile."+item.profile+"]", loader.Origin("Port"))
+		})
+	}
+}
+
+func TestLoaderProfileFlagSet(t *testing.T) {
+	os.Setenv("LOADERTEST_FLAGSET_PROFILE", "dev")
+	defer os.Unsetenv("LOADERTEST_FLAGSET_PROFILE")
+
+	flagSet := flag.NewFlagSet("loadertest", flag.ContinueOnError)
+	require.NoError(t, Into(flagSet, &loadercomponent{}))
+
+	loader := NewLoader(flagSet, Profile("LOADERTEST_FLAGSET_PROFILE"))
+	require.NotNil(t, flagSet.Lookup(ProfileFlag))
+	assert.Nil(t, flag.CommandLine.Lookup(ProfileFlag))
+
+	require.NoError(t, loader.Load(nil))
+	assert.Equal(t, "dev", loader.Profile())
+	assert.Equal(t, "dev", flagSet.Lookup(ProfileFlag).Value.String())
+	assert.Equal(t, OriginEnv, loader.Origin(ProfileFlag))
+
+	require.NoError(t, loader.Load([]string{"-profile", "prod"}))
+	assert.Equal(t, "prod", loader.Profile())
+}
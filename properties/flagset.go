@@ -0,0 +1,10 @@
+package properties
+
+import (
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+)
+
+// FlagSet is the interface for finding the flags that configuration values
+// are assigned to.  It requires only the Lookup and VisitAll methods, so a
+// *flag.FlagSet meets it, as does any other type that provides them.
+type FlagSet = flagutil.FlagSet
@@ -0,0 +1,256 @@
+/*
+
+Package properties reads and writes Java .properties files backed by a
+flag.FlagSet, or any other FlagSet.
+
+Each key in the file names a flag, using the same dotted keys built by
+goflagbuilder.Into.  Keys are separated from values by "=", ":", or
+whitespace.  Lines whose first non-blank character is "#" or "!" are
+comments.  A line ending in a backslash continues onto the next, and the
+escapes \t, \n, \r, \f, and \uXXXX are recognized in keys and values, as are
+backslashes before any other character.
+
+For example:
+
+	# Database settings
+	Database.Host = localhost
+	Database.Port: 5432
+	Greeting = Hello, \
+	           World!
+
+Flags that hold several values, such as slices, accumulate one element per
+line that assigns them.  The first such line in a file replaces the elements
+they held before, where the flag value can remove them all, as those built by
+goflagbuilder.Into can.
+
+*/
+package properties
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/suggest"
+)
+
+// UnknownKeyError describes a key that does not match any flag.  Suggestions
+// holds the names of similarly spelled flags, if any.
+type UnknownKeyError struct {
+	Key         string
+	Line        int
+	Suggestions []string
+}
+
+func (e *UnknownKeyError) Error() string {
+	msg := fmt.Sprintf("unknown key '%s' on line %d", e.Key, e.Line)
+	if s := suggest.Format(e.Suggestions); s != "" {
+		msg += ", " + s
+	}
+	return msg
+}
+
+// Option configures optional behavior of Parse and ParseFile.
+type Option func(*options)
+
+type options struct {
+	unknown func(*UnknownKeyError) error
+}
+
+// IgnoreUnknown skips keys that do not match any flag.
+func IgnoreUnknown() Option {
+	return OnUnknown(func(*UnknownKeyError) {})
+}
+
+// OnUnknown calls fn for each key that does not match any flag, and then
+// continues parsing.
+func OnUnknown(fn func(err *UnknownKeyError)) Option {
+	return func(o *options) {
+		o.unknown = func(err *UnknownKeyError) error {
+			fn(err)
+			return nil
+		}
+	}
+}
+
+// Parse reads the given Reader as a .properties file, setting values to
+// matching flags in the given flagset.  If flagSet is nil, then the global
+// flag.CommandLine FlagSet is used.
+func Parse(in io.Reader, flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	o := options{
+		unknown: func(err *UnknownKeyError) error { return err },
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	assigned := make(map[string]bool)
+
+	var line int
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line++
+		start := line
+
+		str := strings.TrimLeft(scanner.Text(), " \t\f")
+		if str == "" || str[0] == '#' || str[0] == '!' {
+			continue
+		}
+
+		for continues(str) && scanner.Scan() {
+			line++
+			str = str[:len(str)-1] + strings.TrimLeft(scanner.Text(), " \t\f")
+		}
+		if continues(str) {
+			str = str[:len(str)-1]
+		}
+
+		rawKey, rawValue := split(str)
+
+		key, err := unescape(rawKey, start)
+		if err != nil {
+			return err
+		}
+		value, err := unescape(rawValue, start)
+		if err != nil {
+			return err
+		}
+
+		f := flagSet.Lookup(key)
+		if f == nil {
+			err := &UnknownKeyError{
+				Key:         key,
+				Line:        start,
+				Suggestions: suggest.Closest(key, flagutil.Names(flagSet)),
+			}
+			if err := o.unknown(err); err != nil {
+				return err
+			}
+			continue
+		}
+		if !assigned[f.Name] {
+			assigned[f.Name] = true
+			if err := flagutil.Clear(f.Value); err != nil {
+				return err
+			}
+		}
+		if err := f.Value.Set(value); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ParseFile reads the .properties file indicated by filename, setting values
+// to matching flags in the given flagset.  It is identical to calling Parse on
+// a File opened from filename.  If flagSet is nil, the global
+// flag.CommandLine FlagSet is used.
+func ParseFile(filename string, flagSet FlagSet, opts ...Option) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return Parse(in, flagSet, opts...)
+}
+
+// continues reports whether str ends in an odd number of backslashes, and so
+// continues onto the next line.
+func continues(str string) bool {
+	n := 0
+	for i := len(str) - 1; i >= 0 && str[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// split divides a logical line into its still escaped key and value.
+func split(str string) (string, string) {
+	end := len(str)
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' {
+			i++
+		} else if strings.IndexByte("=: \t\f", str[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+
+	key, rest := str[:end], strings.TrimLeft(str[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return key, rest
+}
+
+// unescape replaces the escape sequences in str.
+func unescape(str string, line int) (string, error) {
+	if strings.IndexByte(str, '\\') < 0 {
+		return str, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c != '\\' || i+1 == len(str) {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch str[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, ok := hex(str, i+1)
+			if !ok {
+				return "", fmt.Errorf("malformed \\uxxxx encoding on line %d", line)
+			}
+			i += 4
+
+			// Characters outside the Basic Multilingual Plane are
+			// written as a pair of UTF-16 surrogates.
+			if utf16.IsSurrogate(r) && strings.HasPrefix(str[i+1:], `\u`) {
+				if low, ok := hex(str, i+3); ok {
+					if pair := utf16.DecodeRune(r, low); pair != unicode.ReplacementChar {
+						r = pair
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(str[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
+// hex parses the four hexadecimal digits at str[i:].
+func hex(str string, i int) (rune, bool) {
+	if i+4 > len(str) {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(str[i:i+4], 16, 16)
+	return rune(r), err == nil
+}
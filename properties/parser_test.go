@@ -0,0 +1,121 @@
+package properties
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func makeFlagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.String("Database.Host", "", "")
+	flagSet.Int("Database.Port", 0, "")
+	flagSet.String("Greeting", "", "")
+	flagSet.String("Key With Spaces", "", "")
+	flagSet.String("Empty", "default", "")
+	flagSet.String("Unicode", "", "")
+	return flagSet
+}
+
+func TestParseValid(t *testing.T) {
+	input := `# Database settings
+! Another comment
+Database.Host = localhost
+   Database.Port:5432
+Greeting = Hello, \
+           World!\\
+Key\ With\ Spaces  value\tand\ttabs
+Empty
+Unicode = café 😀 \# \=
+`
+
+	flagSet := makeFlagSet("valid")
+
+	// In order to test default FlagSet
+	commandLine := flag.CommandLine
+	flag.CommandLine = flagSet
+	defer func() { flag.CommandLine = commandLine }()
+
+	if err := Parse(strings.NewReader(input), nil); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := map[string]string{
+		"Database.Host":   "localhost",
+		"Database.Port":   "5432",
+		"Greeting":        `Hello, World!\`,
+		"Key With Spaces": "value\tand\ttabs",
+		"Empty":           "",
+		"Unicode":         "café 😀 # =",
+	}
+	for k, v := range expected {
+		if actual := flagSet.Lookup(k).Value.String(); v != actual {
+			t.Errorf("values for %s not equal\nexpected: %q\n  actual: %q", k, v, actual)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	suite := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "parse unknown key",
+			input: "# comment\nDatabase.Hots = x",
+			err:   "unknown key 'Database.Hots' on line 2, did you mean 'Database.Host'?",
+		},
+		{
+			name:  "parse unknown key after continuation",
+			input: "Greeting = a\\\n  b\nOther = c",
+			err:   "unknown key 'Other' on line 3",
+		},
+		{
+			name:  "parse bad unicode",
+			input: "Greeting = \\u00zz",
+			err:   "malformed \\uxxxx encoding on line 1",
+		},
+		{
+			name:  "parse bad value",
+			input: "Database.Port = http",
+			err:   "parse error",
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			err := Parse(strings.NewReader(item.input), makeFlagSet(item.name))
+			if err == nil {
+				t.Error("expected error:", item.err)
+			} else if item.err != err.Error() {
+				t.Errorf("errors don't match\nexpected: %s\n  actual: %s", item.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseUnknown(t *testing.T) {
+	flagSet := makeFlagSet("unknown")
+	input := "Other = 1\nDatabase.Prot = 2\nDatabase.Port = 3"
+
+	var unknown []string
+	err := Parse(strings.NewReader(input), flagSet, OnUnknown(func(err *UnknownKeyError) {
+		unknown = append(unknown, err.Key+":"+strconv.Itoa(err.Line))
+	}))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if strings.Join(unknown, ",") != "Other:1,Database.Prot:2" {
+		t.Error("unexpected unknown keys:", unknown)
+	}
+	if v := flagSet.Lookup("Database.Port").Value.String(); v != "3" {
+		t.Error("parsing did not continue past unknown keys, got", v)
+	}
+
+	if err := Parse(strings.NewReader(input), makeFlagSet("ignore"), IgnoreUnknown()); err != nil {
+		t.Error("unexpected error:", err)
+	}
+}
@@ -0,0 +1,96 @@
+package properties
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+)
+
+// Write emits every flag in the given flagset as a property that Parse will
+// read back, preceded by its usage string as a comment.  Slice values are
+// written as one line per element.  Characters outside printable ASCII are
+// written as \uXXXX escapes, so the output is also valid ISO 8859-1 as Java
+// expects.  If flagSet is nil, the global flag.CommandLine FlagSet is used.
+func Write(out io.Writer, flagSet FlagSet) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	w := bufio.NewWriter(out)
+	first := true
+
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if !first {
+			w.WriteString("\n")
+		}
+		first = false
+
+		if f.Usage != "" {
+			for _, line := range strings.Split(f.Usage, "\n") {
+				w.WriteString(strings.TrimRight("# "+escapeUnicode(line), " ") + "\n")
+			}
+		}
+
+		items, isSlice := flagutil.SliceItems(f.Value)
+		if !isSlice {
+			items = []string{f.Value.String()}
+		}
+		for _, item := range items {
+			if item == "" {
+				fmt.Fprintf(w, "%s =\n", escape(f.Name, true))
+			} else {
+				fmt.Fprintf(w, "%s = %s\n", escape(f.Name, true), escape(item, false))
+			}
+		}
+	})
+
+	return w.Flush()
+}
+
+// escape returns str with the characters that Parse would interpret
+// specially escaped.  Within a key, all whitespace is escaped; within a value,
+// only leading whitespace.
+func escape(str string, key bool) string {
+	var b strings.Builder
+	for i, r := range str {
+		switch {
+		case r == '\\', r == '=', r == ':', r == '#', r == '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		default:
+			b.WriteString(escapeUnicode(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// escapeUnicode returns str with each character outside printable ASCII
+// written as a \uXXXX escape.
+func escapeUnicode(str string) string {
+	var b strings.Builder
+	for _, r := range str {
+		if r >= 0x20 && r <= 0x7e {
+			b.WriteRune(r)
+			continue
+		}
+		for _, unit := range utf16.Encode([]rune{r}) {
+			fmt.Fprintf(&b, `\u%04x`, unit)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package properties
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+type stringSlice []string
+
+func (s *stringSlice) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func (s *stringSlice) Get() interface{} { return []string(*s) }
+func (s *stringSlice) String() string   { return "[" + strings.Join(*s, ", ") + "]" }
+
+func TestWrite(t *testing.T) {
+	flagSet := makeFlagSet("write")
+	flagSet.Var(&stringSlice{}, "Files", "Files to read\nin order")
+	args := []string{
+		"-Database.Host", "localhost",
+		"-Greeting", " Hello: World! #1\n",
+		"-Unicode", "café 😀",
+		"-Files", "a b", "-Files", "c=d",
+	}
+	if err := flagSet.Parse(args); err != nil {
+		t.Fatal("error parsing args:", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, flagSet); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := `Database.Host = localhost
+
+Database.Port = 0
+
+Empty = default
+
+# Files to read
+# in order
+Files = a b
+Files = c\=d
+
+Greeting = \ Hello\: World\! \#1\n
+
+Key\ With\ Spaces =
+
+Unicode = caf\u00e9 \ud83d\ude00
+`
+	if buf.String() != expected {
+		t.Errorf("output not equal\nexpected: %s\n  actual: %s", expected, buf.String())
+	}
+
+	readSet := makeFlagSet("read")
+	files := &stringSlice{}
+	readSet.Var(files, "Files", "")
+	if err := Parse(&buf, readSet); err != nil {
+		t.Fatal("error parsing output:", err)
+	}
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if actual := readSet.Lookup(f.Name).Value.String(); actual != f.Value.String() {
+			t.Errorf("values for %s not equal\nexpected: %q\n  actual: %q", f.Name, f.Value.String(), actual)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package goflagbuilder
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReloadOnHangup calls Reload each time the process receives SIGHUP, until
+// ctx is done, and then returns ctx.Err().  This re-reads every file, directory,
+// and environment source, while flags keep the values given on the command
+// line.  Each result is reported to the Watcher's OnReload function and, if
+// results is not nil, sent on results as nil or the error.
+//
+// ReloadOnHangup may be used in place of, or alongside, Run:
+//
+//	go w.ReloadOnHangup(ctx, nil)
+func (w *Watcher) ReloadOnHangup(ctx context.Context, results chan<- error) error {
+	return w.reloadOnSignal(ctx, results, syscall.SIGHUP)
+}
+
+func (w *Watcher) reloadOnSignal(ctx context.Context, results chan<- error, sigs ...os.Signal) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sigs...)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-signals:
+			err := w.Reload()
+			if results == nil {
+				continue
+			}
+			select {
+			case results <- err:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+//go:build !windows
+// +build !windows
+
+package goflagbuilder
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadOnHangup(t *testing.T) {
+	// Ignore the default action of SIGHUP, which would end the test, until
+	// the handler is installed.
+	ignore := make(chan os.Signal, 1)
+	signal.Notify(ignore, syscall.SIGHUP)
+	defer signal.Stop(ignore)
+
+	var builds int
+	build := func() (interface{}, *Loader, error) {
+		builds++
+		if builds >= 3 {
+			return nil, nil, errors.New("build failed")
+		}
+		conf := &loadercomponent{Port: builds}
+		return conf, NewLoader(flag.NewFlagSet("hanguptest", flag.ContinueOnError)), nil
+	}
+
+	w, err := NewWatcher(build, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan error)
+	done := make(chan error)
+	go func() { done <- w.ReloadOnHangup(ctx, results) }()
+
+	next := func() error {
+		for {
+			require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+			select {
+			case err := <-results:
+				return err
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	assert.NoError(t, next())
+	assert.Equal(t, 2, w.Current().(*loadercomponent).Port)
+
+	assert.EqualError(t, next(), "build failed")
+	assert.Equal(t, 2, w.Current().(*loadercomponent).Port)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
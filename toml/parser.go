@@ -0,0 +1,238 @@
+/*
+
+Package toml reads TOML documents into values backed by a flag.FlagSet, or
+any other FlagSet.
+
+Tables in the document form the prefixes of dotted keys, so that
+
+	Label = "hello"
+
+	[Foo]
+	Port = 1234
+
+sets the flags Label and Foo.Port.  Strings, integers, floats, and booleans
+are assigned to flags in their usual text form.  Datetimes are assigned in
+RFC 3339 form, which suits the time.Time fields of goflagbuilder.Into, and
+local dates and times in their TOML form, which needs a flag of its own.
+Arrays are assigned element by element, and so are only accepted by flags
+that hold several values, such as slices.  Each table in an array of tables
+is indexed from zero, so that the second [[Servers]] table sets keys such as
+Servers.1.Host.  Into cannot build flags for a slice of structs, whose length
+is not known in advance, but does for a map holding an entry for each index:
+
+	Servers map[string]*Server // with entries "0" and "1"
+
+Errors identify the offending value by line number.
+
+*/
+package toml
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	gotoml "github.com/pelletier/go-toml"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/suggest"
+)
+
+// Error describes a value in a TOML document that could not be assigned to
+// the flag for Key.
+type Error struct {
+	Key  string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("key '%s' on line %d: %v", e.Key, e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// UnknownKeyError describes a value in a TOML document whose key does not
+// match any flag.  Suggestions holds the names of similarly spelled flags, if
+// any.
+type UnknownKeyError struct {
+	Key         string
+	Line        int
+	Suggestions []string
+}
+
+func (e *UnknownKeyError) Error() string {
+	msg := fmt.Sprintf("unknown key '%s' on line %d", e.Key, e.Line)
+	if s := suggest.Format(e.Suggestions); s != "" {
+		msg += ", " + s
+	}
+	return msg
+}
+
+// Option configures optional behavior of Parse and ParseFile.
+type Option func(*options)
+
+type options struct {
+	unknown func(*UnknownKeyError) error
+}
+
+// IgnoreUnknown skips values whose keys do not match any flag.
+func IgnoreUnknown() Option {
+	return OnUnknown(func(*UnknownKeyError) {})
+}
+
+// OnUnknown calls fn for each value whose key does not match any flag, and
+// then continues parsing.
+func OnUnknown(fn func(err *UnknownKeyError)) Option {
+	return func(o *options) {
+		o.unknown = func(err *UnknownKeyError) error {
+			fn(err)
+			return nil
+		}
+	}
+}
+
+type parser struct {
+	options
+	flagSet FlagSet
+}
+
+// Parse reads a TOML document from the given Reader, setting values to
+// matching flags in the given flagset.  If flagSet is nil, then the global
+// flag.CommandLine FlagSet is used.
+func Parse(in io.Reader, flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	tree, err := gotoml.LoadReader(in)
+	if err != nil {
+		return err
+	}
+
+	p := &parser{
+		options: options{
+			unknown: func(err *UnknownKeyError) error { return err },
+		},
+		flagSet: flagSet,
+	}
+	for _, opt := range opts {
+		opt(&p.options)
+	}
+
+	return p.table(tree, "")
+}
+
+// ParseFile reads the TOML document in the file indicated by filename,
+// setting values to matching flags in the given flagset.  If flagSet is nil,
+// the global flag.CommandLine FlagSet is used.
+func ParseFile(filename string, flagSet FlagSet, opts ...Option) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return Parse(in, flagSet, opts...)
+}
+
+// table assigns each value in tree, in document order.
+func (p *parser) table(tree *gotoml.Tree, prefix string) error {
+	if prefix != "" {
+		prefix += "."
+	}
+
+	keys := tree.Keys()
+	sort.SliceStable(keys, func(i, j int) bool {
+		a := tree.GetPositionPath([]string{keys[i]})
+		b := tree.GetPositionPath([]string{keys[j]})
+		return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+	})
+
+	for _, name := range keys {
+		key := prefix + name
+		line := tree.GetPositionPath([]string{name}).Line
+		if err := p.value(tree.GetPath([]string{name}), key, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// value assigns a single value to the flag for key.
+func (p *parser) value(value interface{}, key string, line int) error {
+	f := p.flagSet.Lookup(key)
+
+	switch value := value.(type) {
+	case *gotoml.Tree:
+		if f != nil {
+			return &Error{key, line, errors.New("cannot assign a table")}
+		}
+		return p.table(value, key)
+
+	case []*gotoml.Tree:
+		if f != nil {
+			return &Error{key, line, errors.New("cannot assign an array of tables")}
+		}
+		for i, tree := range value {
+			if err := p.table(tree, key+"."+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if f == nil {
+		return p.options.unknown(&UnknownKeyError{
+			Key:         key,
+			Line:        line,
+			Suggestions: suggest.Closest(key, flagutil.Names(p.flagSet)),
+		})
+	}
+
+	if r := reflect.ValueOf(value); r.Kind() == reflect.Slice {
+		if !flagutil.Multiple(f.Value) {
+			return &Error{key, line, errors.New("cannot assign an array")}
+		}
+
+		for i := 0; i < r.Len(); i++ {
+			item := r.Index(i).Interface()
+			if reflect.ValueOf(item).Kind() == reflect.Slice {
+				return &Error{key, line, errors.New("cannot assign a nested array")}
+			}
+			if err := f.Value.Set(scalar(item)); err != nil {
+				return &Error{key, line, err}
+			}
+		}
+		return nil
+	}
+
+	if err := f.Value.Set(scalar(value)); err != nil {
+		return &Error{key, line, err}
+	}
+	return nil
+}
+
+// scalar formats a TOML value as flag text.
+func scalar(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case uint64:
+		return strconv.FormatUint(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,174 @@
+package toml
+
+import (
+	"errors"
+	"flag"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagtest"
+)
+
+type timeValue struct{ t time.Time }
+
+func (v *timeValue) Set(s string) error {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	v.t = t
+	return err
+}
+
+func (v *timeValue) String() string { return v.t.UTC().Format(time.RFC3339) }
+
+func makeFlagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.String("Label", "", "")
+	flagSet.Int("Foo.Port", 9999, "")
+	flagSet.Bool("Foo.Secure", false, "")
+	flagSet.Float64("Ratio", 0, "")
+	flagSet.Var(&flagtest.IntSlice{}, "Ports", "")
+	flagSet.Var(&timeValue{}, "Started", "")
+	flagSet.String("Birthday", "", "")
+	flagSet.String("Servers.0.Host", "", "")
+	flagSet.String("Servers.1.Host", "", "")
+	return flagSet
+}
+
+func TestParseValid(t *testing.T) {
+	input := `Label = "hello"
+Ratio = 0.5
+Ports = [80, 443]
+Started = 2018-10-15T08:30:00-04:00
+Birthday = 1979-05-27T07:32:00
+"Foo.Secure" = true
+
+[Foo]
+Port = 1234
+
+[[Servers]]
+Host = "a.example"
+
+[[Servers]]
+Host = "b.example"
+`
+
+	flagSet := makeFlagSet("valid")
+
+	// In order to test default FlagSet
+	commandLine := flag.CommandLine
+	flag.CommandLine = flagSet
+	defer func() { flag.CommandLine = commandLine }()
+
+	if err := Parse(strings.NewReader(input), nil); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := map[string]string{
+		"Label":          "hello",
+		"Ratio":          "0.5",
+		"Ports":          "[80, 443]",
+		"Started":        "2018-10-15T12:30:00Z",
+		"Birthday":       "1979-05-27T07:32:00",
+		"Foo.Port":       "1234",
+		"Foo.Secure":     "true",
+		"Servers.0.Host": "a.example",
+		"Servers.1.Host": "b.example",
+	}
+	for k, v := range expected {
+		if actual := flagSet.Lookup(k).Value.String(); v != actual {
+			t.Errorf("values for %s not equal\nexpected: %v\n  actual: %v", k, v, actual)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	suite := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "parse syntax error",
+			input: "Label = \n",
+			err:   "(2, 1): expecting a value",
+		},
+		{
+			name:  "parse unknown key",
+			input: "Label = \"x\"\n[Foo]\nProt = 10",
+			err:   "unknown key 'Foo.Prot' on line 3, did you mean 'Foo.Port'?",
+		},
+		{
+			name:  "parse type mismatch",
+			input: "\n\nRatio = \"half\"",
+			err:   `key 'Ratio' on line 3: parse error`,
+		},
+		{
+			name:  "parse table for value",
+			input: "[Label]\nx = 1",
+			err:   "key 'Label' on line 1: cannot assign a table",
+		},
+		{
+			name:  "parse array for value",
+			input: "Label = [\"a\"]",
+			err:   "key 'Label' on line 1: cannot assign an array",
+		},
+		{
+			name:  "parse nested array",
+			input: "Ports = [[1], [2]]",
+			err:   "key 'Ports' on line 1: cannot assign a nested array",
+		},
+		{
+			name:  "parse unknown table index",
+			input: "[[Servers]]\nHost = \"a\"\n[[Servers]]\n[[Servers]]\nHost = \"c\"",
+			err:   "unknown key 'Servers.2.Host' on line 5, did you mean 'Servers.0.Host' or 'Servers.1.Host'?",
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			err := Parse(strings.NewReader(item.input), makeFlagSet(item.name))
+			if err == nil {
+				t.Error("expected error:", item.err)
+			} else if item.err != err.Error() {
+				t.Errorf("errors don't match\nexpected: %s\n  actual: %s", item.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseErrorUnwrap(t *testing.T) {
+	err := Parse(strings.NewReader("[Foo]\nPort = \"http\""), makeFlagSet("unwrap"))
+
+	var tomlErr *Error
+	if !errors.As(err, &tomlErr) {
+		t.Fatal("expected *Error, got", err)
+	}
+	if tomlErr.Line != 2 || tomlErr.Key != "Foo.Port" || tomlErr.Err == nil {
+		t.Errorf("unexpected error fields: %#v", tomlErr)
+	}
+}
+
+func TestParseUnknown(t *testing.T) {
+	flagSet := makeFlagSet("unknown")
+	input := "Other = 1\n[Foo]\nProt = 1\nPort = 2"
+
+	var unknown []string
+	err := Parse(strings.NewReader(input), flagSet, OnUnknown(func(err *UnknownKeyError) {
+		unknown = append(unknown, err.Key+":"+strconv.Itoa(err.Line))
+	}))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if strings.Join(unknown, ",") != "Other:1,Foo.Prot:3" {
+		t.Error("unexpected unknown keys:", unknown)
+	}
+	if v := flagSet.Lookup("Foo.Port").Value.String(); v != "2" {
+		t.Error("parsing did not continue past unknown keys, got", v)
+	}
+
+	if err := Parse(strings.NewReader(input), makeFlagSet("ignore"), IgnoreUnknown()); err != nil {
+		t.Error("unexpected error:", err)
+	}
+}
@@ -1,10 +1,12 @@
 package goflagbuilder
 
 import (
+	"errors"
 	"flag"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type flagKind interface {
@@ -13,10 +15,30 @@ type flagKind interface {
 	String(v reflect.Value) string
 }
 
+// removeKind is implemented by kinds holding several elements, one of which
+// may be removed by value.
+type removeKind interface {
+	Remove(v reflect.Value, s string) error
+}
+
+// clearKind is implemented by kinds holding several elements, which may all be
+// removed.
+type clearKind interface {
+	Clear(v reflect.Value) error
+}
+
+// resetKind is implemented by kinds that restore their default in some way
+// other than assigning it directly.
+type resetKind interface {
+	Reset(v reflect.Value, def reflect.Value)
+}
+
 type value struct {
 	value  reflect.Value
+	def    reflect.Value
 	kind   flagKind
 	isBool bool
+	env    []string
 }
 
 func (v value) Set(s string) error {
@@ -34,8 +56,61 @@ func (v value) String() string {
 
 func (v value) IsBoolFlag() bool { return v.isBool }
 
-func find(k reflect.Kind) flagKind {
-	switch k {
+// EnvNames returns the environment variables named by the field's "env" tag.
+func (v value) EnvNames() []string { return v.env }
+
+// Remove removes each element equal to s from a slice value.
+func (v value) Remove(s string) error {
+	kind, ok := v.kind.(removeKind)
+	if !ok {
+		return errors.New("value does not hold multiple elements")
+	}
+	return kind.Remove(v.value, s)
+}
+
+// Clear removes every element of a slice value.
+func (v value) Clear() error {
+	kind, ok := v.kind.(clearKind)
+	if !ok {
+		return errors.New("value does not hold multiple elements")
+	}
+	return kind.Clear(v.value)
+}
+
+// Reset restores the value it had when the flag was built.
+func (v value) Reset() error {
+	if !v.def.IsValid() {
+		return errors.New("value has no default")
+	}
+
+	if kind, ok := v.kind.(resetKind); ok {
+		kind.Reset(v.value, copyValue(v.def))
+	} else {
+		v.value.Set(copyValue(v.def))
+	}
+	return nil
+}
+
+// copyValue returns a copy of r that shares no slice storage with it.
+func copyValue(r reflect.Value) reflect.Value {
+	c := reflect.New(r.Type()).Elem()
+	if r.Kind() == reflect.Slice && !r.IsNil() {
+		c.Set(reflect.MakeSlice(r.Type(), r.Len(), r.Len()))
+		reflect.Copy(c, r)
+	} else {
+		c.Set(r)
+	}
+	return c
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func find(t reflect.Type) flagKind {
+	if t == timeType {
+		return timeKind{}
+	}
+
+	switch t.Kind() {
 	case reflect.Bool:
 		return boolKind{}
 
@@ -66,13 +141,13 @@ func isGetter(r reflect.Value) bool {
 }
 
 func findKind(r reflect.Value) flagKind {
-	kind := find(r.Kind())
+	kind := find(r.Type())
 	if kind != nil {
 		return kind
 	}
 
 	if r.Kind() == reflect.Slice {
-		kind := sliceKind{itemKind: find(r.Type().Elem().Kind())}
+		kind := sliceKind{itemKind: find(r.Type().Elem())}
 		if kind.itemKind != nil {
 			return kind
 		}
@@ -194,6 +269,25 @@ func (stringKind) Get(r reflect.Value) interface{} { return r.String() }
 
 func (stringKind) String(r reflect.Value) string { return r.String() }
 
+// time.Time Kind
+
+type timeKind struct{}
+
+func (timeKind) Set(r reflect.Value, s string) error {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	r.Set(reflect.ValueOf(t))
+	return nil
+}
+
+func (timeKind) Get(r reflect.Value) interface{} { return r.Interface() }
+
+func (timeKind) String(r reflect.Value) string {
+	return r.Interface().(time.Time).Format(time.RFC3339Nano)
+}
+
 // flag.Getter Value
 
 type getterKind struct{}
@@ -241,6 +335,28 @@ func (v sliceKind) Set(r reflect.Value, s string) error {
 	return nil
 }
 
+func (v sliceKind) Remove(r reflect.Value, s string) error {
+	itemValue := reflect.New(r.Type().Elem())
+	if err := v.itemKind.Set(itemValue.Elem(), s); err != nil {
+		return err
+	}
+
+	kept := reflect.MakeSlice(r.Type(), 0, r.Len())
+	for i := 0; i < r.Len(); i++ {
+		if r.Index(i).Interface() != itemValue.Elem().Interface() {
+			kept = reflect.Append(kept, r.Index(i))
+		}
+	}
+
+	r.Set(kept)
+	return nil
+}
+
+func (sliceKind) Clear(r reflect.Value) error {
+	r.Set(reflect.MakeSlice(r.Type(), 0, 0))
+	return nil
+}
+
 func (sliceKind) Get(r reflect.Value) interface{} { return r.Interface() }
 
 func (sliceKind) String(r reflect.Value) string {
@@ -275,6 +391,40 @@ func (v mapKind) Set(r reflect.Value, s string) error {
 	return nil
 }
 
+func (v mapKind) Remove(r reflect.Value, s string) error {
+	kind, ok := v.valueKind.(removeKind)
+	if !ok {
+		return errors.New("value does not hold multiple elements")
+	}
+
+	itemValue := copyValue(r.MapIndex(v.keyval))
+	if err := kind.Remove(itemValue, s); err != nil {
+		return err
+	}
+
+	r.SetMapIndex(v.keyval, itemValue)
+	return nil
+}
+
+func (v mapKind) Clear(r reflect.Value) error {
+	kind, ok := v.valueKind.(clearKind)
+	if !ok {
+		return errors.New("value does not hold multiple elements")
+	}
+
+	itemValue := reflect.New(r.Type().Elem()).Elem()
+	if err := kind.Clear(itemValue); err != nil {
+		return err
+	}
+
+	r.SetMapIndex(v.keyval, itemValue)
+	return nil
+}
+
+func (v mapKind) Reset(r reflect.Value, def reflect.Value) {
+	r.SetMapIndex(v.keyval, def)
+}
+
 func (v mapKind) Get(r reflect.Value) interface{} {
 	return v.valueKind.Get(r.MapIndex(v.keyval))
 }
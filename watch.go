@@ -0,0 +1,207 @@
+package goflagbuilder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// Build constructs a fresh configuration, typically a pointer to a struct of
+// defaults, and a Loader whose flag set was populated from it with Into.  The
+// Loader must have its own flag set, not flag.CommandLine, as a new one is
+// needed for each reload.
+type Build func() (config interface{}, loader *Loader, err error)
+
+// WatchOption configures optional behavior of a Watcher.
+type WatchOption func(*Watcher)
+
+// PollInterval sets how often a Watcher checks its files for changes.  The
+// default is five seconds.
+func PollInterval(d time.Duration) WatchOption {
+	return func(w *Watcher) { w.interval = d }
+}
+
+// Validate sets a function that checks each newly loaded configuration.  A
+// configuration that fails is discarded, and the current one is kept.
+func Validate(fn func(config interface{}) error) WatchOption {
+	return func(w *Watcher) { w.validate = fn }
+}
+
+// OnReload sets a function called after each reload with the old and new
+// configurations.  If the reload failed, err is set, new is nil, and old
+// remains current.
+func OnReload(fn func(old, new interface{}, err error)) WatchOption {
+	return func(w *Watcher) { w.onReload = fn }
+}
+
+// Watcher holds a configuration and reloads it when its files change.  Each
+// reload builds and loads a whole new configuration with the original command
+// line arguments, so that the environment and flags still take precedence over
+// the files, and replaces the current one only if that succeeds.  A
+// configuration is never modified once it is current, so readers see either
+// the old or the new values, never a mixture.
+//
+// Changes are found by polling the modification times and sizes of the files
+// and directories reported by Loader.Files, so a directory is only seen to
+// change when its entries do, as when Kubernetes updates a volume.
+type Watcher struct {
+	build    Build
+	args     []string
+	interval time.Duration
+	validate func(interface{}) error
+	onReload func(old, new interface{}, err error)
+
+	reloadMu sync.Mutex
+
+	mu      sync.RWMutex
+	current interface{}
+	loader  *Loader
+	stamps  map[string]stamp
+}
+
+// stamp identifies a version of a file, or records that it is missing.
+type stamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher builds and loads the first configuration with the given command
+// line arguments.  It fails if that configuration does not load or validate.
+func NewWatcher(build Build, args []string, opts ...WatchOption) (*Watcher, error) {
+	w := &Watcher{
+		build:    build,
+		args:     args,
+		interval: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	config, loader, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+
+	w.current = config
+	w.loader = loader
+	w.stamps = stamps(loader.Files())
+	return w, nil
+}
+
+// Current returns the current configuration.
+func (w *Watcher) Current() interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Loader returns the Loader of the current configuration, which reports the
+// origin of its values.
+func (w *Watcher) Loader() *Loader {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.loader
+}
+
+// Reload builds and loads a new configuration, and makes it current if it
+// loads and validates.  Otherwise the current configuration is kept and the
+// error returned.  Either way, the OnReload function is called.
+func (w *Watcher) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	config, loader, err := w.load()
+
+	w.mu.Lock()
+	old := w.current
+	if err == nil {
+		w.current = config
+		w.loader = loader
+		w.stamps = stamps(loader.Files())
+	} else {
+		// Wait for another change before trying again.
+		w.stamps = stamps(w.loader.Files())
+	}
+	w.mu.Unlock()
+
+	if w.onReload != nil {
+		w.onReload(old, config, err)
+	}
+	return err
+}
+
+// Run polls the files of the current configuration until ctx is done, calling
+// Reload whenever one of them changes.  Errors are reported through OnReload.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if w.changed() {
+				w.Reload()
+			}
+		}
+	}
+}
+
+// changed reports whether any file of the current configuration has changed
+// since it was loaded.
+func (w *Watcher) changed() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for filename, s := range w.stamps {
+		if stampOf(filename) != s {
+			return true
+		}
+	}
+	return false
+}
+
+// load builds, loads, and validates a new configuration.
+func (w *Watcher) load() (interface{}, *Loader, error) {
+	config, loader, err := w.build()
+	if err != nil {
+		return nil, nil, err
+	}
+	if loader == nil {
+		return nil, nil, errors.New("build returned no loader")
+	}
+
+	if err := loader.Load(w.args); err != nil {
+		return nil, nil, err
+	}
+
+	if w.validate != nil {
+		if err := w.validate(config); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return config, loader, nil
+}
+
+func stamps(filenames []string) map[string]stamp {
+	stamps := make(map[string]stamp, len(filenames))
+	for _, filename := range filenames {
+		stamps[filename] = stampOf(filename)
+	}
+	return stamps
+}
+
+// stampOf returns the current stamp of filename.  A file that cannot be read
+// has the zero stamp, so it is only changed once it appears again.
+func stampOf(filename string) stamp {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{true, info.ModTime(), info.Size()}
+}
@@ -0,0 +1,117 @@
+package goflagbuilder
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reload struct {
+	old, new interface{}
+	err      error
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goflagbuilder-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.conf")
+	write := func(content string, age time.Duration) {
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+		mtime := time.Now().Add(-age)
+		require.NoError(t, os.Chtimes(filename, mtime, mtime))
+	}
+	write("Domain = first.example\nPort = 1\nLabel = file", time.Hour)
+
+	build := func() (interface{}, *Loader, error) {
+		conf := &loadercomponent{}
+		flagSet := flag.NewFlagSet("watchtest", flag.ContinueOnError)
+		if err := Into(flagSet, conf); err != nil {
+			return nil, nil, err
+		}
+		return conf, NewLoader(flagSet, File(filename), Env()), nil
+	}
+
+	reloads := make(chan reload, 1)
+	w, err := NewWatcher(build, []string{"-Label", "flags"},
+		PollInterval(time.Millisecond),
+		Validate(func(config interface{}) error {
+			if config.(*loadercomponent).Port <= 0 {
+				return errors.New("port must be positive")
+			}
+			return nil
+		}),
+		OnReload(func(old, new interface{}, err error) {
+			reloads <- reload{old, new, err}
+		}),
+	)
+	require.NoError(t, err)
+
+	first := w.Current().(*loadercomponent)
+	assert.Equal(t, &loadercomponent{Domain: "first.example", Port: 1, Label: "flags"}, first)
+	assert.Equal(t, filename, w.Loader().Origin("Domain"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	next := func() reload {
+		select {
+		case r := <-reloads:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for reload")
+			return reload{}
+		}
+	}
+
+	write("Domain = second.example\nPort = 2\nLabel = file", time.Minute)
+	r := next()
+	require.NoError(t, r.err)
+	second := w.Current().(*loadercomponent)
+	assert.True(t, r.old == first && r.new == second)
+	assert.Equal(t, &loadercomponent{Domain: "second.example", Port: 2, Label: "flags"}, second)
+	assert.Equal(t, "first.example", first.Domain, "old configuration modified")
+
+	write("Domain = third.example\nPort = 0", time.Second)
+	r = next()
+	assert.EqualError(t, r.err, "port must be positive")
+	assert.Nil(t, r.new)
+	assert.True(t, w.Current() == second)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	write("Port = 3", 0)
+	require.NoError(t, w.Reload())
+	assert.Equal(t, 3, w.Current().(*loadercomponent).Port)
+	<-reloads
+
+	os.Remove(filename)
+	assert.True(t, w.changed())
+	assert.Error(t, w.Reload())
+	assert.Equal(t, 3, w.Current().(*loadercomponent).Port)
+	assert.False(t, w.changed(), "missing file changed again")
+
+	write("Port = 4", 0)
+	assert.True(t, w.changed())
+}
+
+func TestWatcherInitialError(t *testing.T) {
+	build := func() (interface{}, *Loader, error) {
+		flagSet := flag.NewFlagSet("watchtest", flag.ContinueOnError)
+		return nil, NewLoader(flagSet, File("/goflagbuilder-bad-test.conf")), nil
+	}
+
+	_, err := NewWatcher(build, nil)
+	assert.True(t, os.IsNotExist(err), "unexpected error: %v", err)
+}
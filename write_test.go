@@ -0,0 +1,87 @@
+package goflagbuilder
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/conf"
+	"github.com/BellerophonMobile/goflagbuilder/v2/env"
+	"github.com/BellerophonMobile/goflagbuilder/v2/json"
+	"github.com/BellerophonMobile/goflagbuilder/v2/properties"
+)
+
+type writecomponent struct {
+	Name  string
+	Port  int
+	Files []string
+}
+
+// writeAndRead writes the flags of written with write and reads them back
+// into result using read.
+func writeAndRead(t *testing.T, written, result *writecomponent,
+	write func(*bytes.Buffer, *flag.FlagSet) error,
+	read func(*bytes.Buffer, *flag.FlagSet) error) {
+
+	flagSet := flag.NewFlagSet("app", flag.ContinueOnError)
+	require.NoError(t, Into(flagSet, written))
+
+	var buf bytes.Buffer
+	require.NoError(t, write(&buf, flagSet))
+
+	readSet := flag.NewFlagSet("app", flag.ContinueOnError)
+	require.NoError(t, Into(readSet, result))
+	require.NoError(t, read(&buf, readSet), buf.String())
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	suite := []struct {
+		name  string
+		write func(*bytes.Buffer, *flag.FlagSet) error
+		read  func(*bytes.Buffer, *flag.FlagSet) error
+	}{
+		{
+			name:  "conf",
+			write: func(buf *bytes.Buffer, flagSet *flag.FlagSet) error { return conf.Write(buf, flagSet) },
+			read:  func(buf *bytes.Buffer, flagSet *flag.FlagSet) error { return conf.Parse(buf, flagSet) },
+		},
+		{
+			name:  "json",
+			write: func(buf *bytes.Buffer, flagSet *flag.FlagSet) error { return json.Write(buf, flagSet) },
+			read:  func(buf *bytes.Buffer, flagSet *flag.FlagSet) error { return json.Parse(buf, flagSet) },
+		},
+		{
+			name:  "properties",
+			write: func(buf *bytes.Buffer, flagSet *flag.FlagSet) error { return properties.Write(buf, flagSet) },
+			read:  func(buf *bytes.Buffer, flagSet *flag.FlagSet) error { return properties.Parse(buf, flagSet) },
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			for _, files := range [][]string{{"a.log"}, {"b.log", "c.log"}} {
+				written := &writecomponent{Name: "tuna", Port: 8080, Files: files}
+
+				// The slice read back replaces its default.
+				result := &writecomponent{Name: "sushi", Port: 80, Files: []string{"a.log"}}
+				writeAndRead(t, written, result, item.write, item.read)
+				assert.Equal(t, written, result)
+			}
+		})
+	}
+}
+
+func TestWriteRoundTrip_Env(t *testing.T) {
+	written := &writecomponent{Name: "tuna", Port: 8080, Files: []string{"b.log", "c.log"}}
+	result := &writecomponent{Name: "sushi", Port: 80, Files: []string{"a.log"}}
+
+	writeAndRead(t, written, result,
+		func(buf *bytes.Buffer, flagSet *flag.FlagSet) error { return env.Write(buf, flagSet) },
+		func(buf *bytes.Buffer, flagSet *flag.FlagSet) error { return env.ParseReader(buf, flagSet) })
+
+	// A slice is not written, so it keeps its default.
+	assert.Equal(t, &writecomponent{Name: "tuna", Port: 8080, Files: []string{"a.log"}}, result)
+}
@@ -0,0 +1,289 @@
+/*
+
+Package yaml reads YAML documents into values backed by a flag.FlagSet, or
+any other FlagSet.
+
+Mappings in the document form the path segments of dotted keys, so that
+
+	Foo:
+	  Port: 1234
+	Bar.Label: hello
+
+sets the flags Foo.Port and Bar.Label.  Scalars are assigned to flags in
+their text form.  Sequences are assigned element by element, and so are only
+accepted by flags that hold several values, such as slices.  Null values are
+skipped.
+
+Each key may only be set once.  In particular, aliases and merge keys that
+would set a key already set elsewhere are rejected, unless the
+AllowDuplicates option is given.  In that case the keys of a mapping override
+those merged into it, as YAML specifies, and otherwise later values override
+earlier ones.
+
+Errors identify the offending node by line and column.
+
+*/
+package yaml
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagutil"
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/suggest"
+)
+
+// Error describes a node in a YAML document that could not be assigned to the
+// flag for Key.
+type Error struct {
+	Key    string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("key '%s' at line %d, column %d: %v", e.Key, e.Line, e.Column, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// UnknownKeyError describes a node in a YAML document whose key does not match
+// any flag.  Suggestions holds the names of similarly spelled flags, if any.
+type UnknownKeyError struct {
+	Key         string
+	Line        int
+	Column      int
+	Suggestions []string
+}
+
+func (e *UnknownKeyError) Error() string {
+	msg := fmt.Sprintf("unknown key '%s' at line %d, column %d", e.Key, e.Line, e.Column)
+	if s := suggest.Format(e.Suggestions); s != "" {
+		msg += ", " + s
+	}
+	return msg
+}
+
+// Option configures optional behavior of Parse and ParseFile.
+type Option func(*options)
+
+type options struct {
+	unknown    func(*UnknownKeyError) error
+	duplicates bool
+}
+
+// IgnoreUnknown skips nodes whose keys do not match any flag.
+func IgnoreUnknown() Option {
+	return OnUnknown(func(*UnknownKeyError) {})
+}
+
+// OnUnknown calls fn for each node whose key does not match any flag, and
+// then continues parsing.
+func OnUnknown(fn func(err *UnknownKeyError)) Option {
+	return func(o *options) {
+		o.unknown = func(err *UnknownKeyError) error {
+			fn(err)
+			return nil
+		}
+	}
+}
+
+// AllowDuplicates permits a key to be set more than once, including through
+// aliases and merge keys.
+func AllowDuplicates() Option {
+	return func(o *options) { o.duplicates = true }
+}
+
+type parser struct {
+	options
+	flagSet FlagSet
+	set     map[string]*yaml.Node
+}
+
+// Parse reads a YAML document from the given Reader, setting values to
+// matching flags in the given flagset.  If flagSet is nil, then the global
+// flag.CommandLine FlagSet is used.
+func Parse(in io.Reader, flagSet FlagSet, opts ...Option) error {
+	if flagutil.IsNil(flagSet) {
+		flagSet = flag.CommandLine
+	}
+
+	var doc yaml.Node
+	if err := yaml.NewDecoder(in).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	p := &parser{
+		options: options{
+			unknown: func(err *UnknownKeyError) error { return err },
+		},
+		flagSet: flagSet,
+		set:     make(map[string]*yaml.Node),
+	}
+	for _, opt := range opts {
+		opt(&p.options)
+	}
+
+	// A document holding only null, such as "---" or "~", sets nothing.
+	root := doc.Content[0]
+	if resolve(root).ShortTag() == "!!null" {
+		return nil
+	}
+	if resolve(root).Kind != yaml.MappingNode {
+		return fmt.Errorf("document at line %d, column %d is not a mapping", root.Line, root.Column)
+	}
+	return p.node(root, "")
+}
+
+// ParseFile reads the YAML document in the file indicated by filename,
+// setting values to matching flags in the given flagset.  If flagSet is nil,
+// the global flag.CommandLine FlagSet is used.
+func ParseFile(filename string, flagSet FlagSet, opts ...Option) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return Parse(in, flagSet, opts...)
+}
+
+// node assigns the given node to the flag for key, or descends into it.
+func (p *parser) node(node *yaml.Node, key string) error {
+	target := resolve(node)
+	f := p.flagSet.Lookup(key)
+
+	switch target.Kind {
+	case yaml.MappingNode:
+		if f != nil {
+			return p.error(node, key, errors.New("cannot assign a mapping"))
+		}
+		return p.mapping(target, key)
+
+	case yaml.SequenceNode:
+		if f == nil {
+			return p.unknown(node, key)
+		}
+		return p.sequence(node, target, f)
+	}
+
+	if target.ShortTag() == "!!null" {
+		return nil
+	}
+	if f == nil {
+		return p.unknown(node, key)
+	}
+	if err := p.mark(node, key); err != nil {
+		return err
+	}
+	if err := f.Value.Set(target.Value); err != nil {
+		return p.error(node, key, err)
+	}
+	return nil
+}
+
+// mapping descends into each member of a mapping.  Merged mappings are
+// handled first, so that the mapping's own keys override them.
+func (p *parser) mapping(node *yaml.Node, key string) error {
+	prefix := key
+	if prefix != "" {
+		prefix += "."
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].ShortTag() != "!!merge" {
+			continue
+		}
+
+		merged := node.Content[i+1]
+		sources := []*yaml.Node{merged}
+		if resolve(merged).Kind == yaml.SequenceNode {
+			sources = resolve(merged).Content
+		}
+
+		for _, source := range sources {
+			if resolve(source).Kind != yaml.MappingNode {
+				return fmt.Errorf("merge at line %d, column %d is not a mapping", source.Line, source.Column)
+			}
+			if err := p.mapping(resolve(source), key); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i], node.Content[i+1]
+		if name.ShortTag() == "!!merge" {
+			continue
+		}
+		if err := p.node(value, prefix+resolve(name).Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sequence assigns each element of a sequence to f.
+func (p *parser) sequence(node, target *yaml.Node, f *flag.Flag) error {
+	if !flagutil.Multiple(f.Value) {
+		return p.error(node, f.Name, errors.New("cannot assign a sequence"))
+	}
+	if err := p.mark(node, f.Name); err != nil {
+		return err
+	}
+
+	for _, item := range target.Content {
+		value := resolve(item)
+		if value.Kind != yaml.ScalarNode {
+			return p.error(item, f.Name, errors.New("cannot assign a nested sequence or mapping"))
+		}
+		if value.ShortTag() == "!!null" {
+			continue
+		}
+		if err := f.Value.Set(value.Value); err != nil {
+			return p.error(item, f.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// mark records that key is set by node, failing if it was already set.
+func (p *parser) mark(node *yaml.Node, key string) error {
+	if previous, ok := p.set[key]; ok && !p.duplicates {
+		return p.error(node, key, fmt.Errorf("already set at line %d, column %d", previous.Line, previous.Column))
+	}
+	p.set[key] = node
+	return nil
+}
+
+func (p *parser) error(node *yaml.Node, key string, err error) error {
+	return &Error{Key: key, Line: node.Line, Column: node.Column, Err: err}
+}
+
+func (p *parser) unknown(node *yaml.Node, key string) error {
+	return p.options.unknown(&UnknownKeyError{
+		Key:         key,
+		Line:        node.Line,
+		Column:      node.Column,
+		Suggestions: suggest.Closest(key, flagutil.Names(p.flagSet)),
+	})
+}
+
+// resolve follows aliases to the node they refer to.
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
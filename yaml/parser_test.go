@@ -0,0 +1,223 @@
+package yaml
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/BellerophonMobile/goflagbuilder/v2/internal/flagtest"
+)
+
+func makeFlagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.String("Label", "", "")
+	flagSet.String("Foo.Domain", "example.com", "")
+	flagSet.Int("Foo.Port", 9999, "")
+	flagSet.Bool("Foo.Secure", false, "")
+	flagSet.Int("Bar.Port", 0, "")
+	flagSet.Var(&flagtest.IntSlice{}, "Ports", "")
+	return flagSet
+}
+
+func TestParseValid(t *testing.T) {
+	suite := []struct {
+		name  string
+		input string
+		opts  []Option
+		end   map[string]string
+	}{
+		{
+			name:  "parse empty document",
+			input: "# nothing here\n",
+			end: map[string]string{
+				"Foo.Port": "9999",
+			},
+		},
+		{
+			name:  "parse document marker only",
+			input: "---\n",
+			end: map[string]string{
+				"Foo.Port": "9999",
+			},
+		},
+		{
+			name:  "parse null document",
+			input: "~\n",
+			end: map[string]string{
+				"Foo.Port": "9999",
+			},
+		},
+		{
+			name: "parse nested mappings",
+			input: `
+Label: hello
+Foo:
+  Port: 1234
+  Secure: true
+Bar.Port: 80
+Ports: [80, ~, 443]
+`,
+			end: map[string]string{
+				"Label":      "hello",
+				"Foo.Domain": "example.com",
+				"Foo.Port":   "1234",
+				"Foo.Secure": "true",
+				"Bar.Port":   "80",
+				"Ports":      "[80, 443]",
+			},
+		},
+		{
+			name: "parse aliases",
+			input: `
+Foo:
+  Port: &port 1234
+  Domain: sushi.example
+Bar:
+  Port: *port
+`,
+			end: map[string]string{
+				"Foo.Port": "1234",
+				"Bar.Port": "1234",
+			},
+		},
+		{
+			name: "parse allowed merge",
+			input: `
+Foo:
+  Port: 7
+  <<: &defaults
+    Port: 1234
+    Domain: sushi.example
+`,
+			opts: []Option{AllowDuplicates(), IgnoreUnknown()},
+			end: map[string]string{
+				"Foo.Port":   "7",
+				"Foo.Domain": "sushi.example",
+			},
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			flagSet := makeFlagSet(item.name)
+
+			// In order to test default FlagSet
+			commandLine := flag.CommandLine
+			flag.CommandLine = flagSet
+			defer func() { flag.CommandLine = commandLine }()
+
+			if err := Parse(strings.NewReader(item.input), nil, item.opts...); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+
+			for k, v := range item.end {
+				if actual := flagSet.Lookup(k).Value.String(); v != actual {
+					t.Errorf("values for %s not equal\nexpected: %v\n  actual: %v", k, v, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	suite := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "parse sequence document",
+			input: "- 1\n- 2",
+			err:   "document at line 1, column 1 is not a mapping",
+		},
+		{
+			name:  "parse syntax error",
+			input: "Foo: [1\n",
+			err:   "yaml: line 1: did not find expected ',' or ']'",
+		},
+		{
+			name:  "parse unknown key",
+			input: "Foo:\n  Prot: 10",
+			err:   "unknown key 'Foo.Prot' at line 2, column 9, did you mean 'Foo.Port'?",
+		},
+		{
+			name:  "parse type mismatch",
+			input: "Foo:\n  Secure: maybe",
+			err:   "key 'Foo.Secure' at line 2, column 11: parse error",
+		},
+		{
+			name:  "parse mapping for value",
+			input: "Label:\n  Text: hi",
+			err:   "key 'Label' at line 2, column 3: cannot assign a mapping",
+		},
+		{
+			name:  "parse sequence for value",
+			input: "Label: [a]",
+			err:   "key 'Label' at line 1, column 8: cannot assign a sequence",
+		},
+		{
+			name:  "parse nested sequence",
+			input: "Ports: [[1]]",
+			err:   "key 'Ports' at line 1, column 9: cannot assign a nested sequence or mapping",
+		},
+		{
+			name:  "parse duplicate key",
+			input: "Foo:\n  Port: 1\nFoo.Port: 2",
+			err:   "key 'Foo.Port' at line 3, column 11: already set at line 2, column 9",
+		},
+		{
+			name:  "parse duplicate through merge",
+			input: "Foo: &foo\n  Port: 1\nBar:\n  <<: *foo\n  Port: 2\n",
+			err:   "key 'Bar.Port' at line 5, column 9: already set at line 2, column 9",
+		},
+		{
+			name:  "parse bad merge",
+			input: "Foo:\n  <<: 1\n",
+			err:   "merge at line 2, column 7 is not a mapping",
+		},
+	}
+
+	for _, item := range suite {
+		t.Run(item.name, func(t *testing.T) {
+			err := Parse(strings.NewReader(item.input), makeFlagSet(item.name))
+			if err == nil {
+				t.Error("expected error:", item.err)
+			} else if item.err != err.Error() {
+				t.Errorf("errors don't match\nexpected: %s\n  actual: %s", item.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseErrorUnwrap(t *testing.T) {
+	err := Parse(strings.NewReader("Foo:\n  Port: http"), makeFlagSet("unwrap"))
+
+	var yamlErr *Error
+	if !errors.As(err, &yamlErr) {
+		t.Fatal("expected *Error, got", err)
+	}
+	if yamlErr.Line != 2 || yamlErr.Column != 9 || yamlErr.Key != "Foo.Port" || yamlErr.Err == nil {
+		t.Errorf("unexpected error fields: %#v", yamlErr)
+	}
+}
+
+func TestParseUnknown(t *testing.T) {
+	flagSet := makeFlagSet("unknown")
+	input := "Other: [1]\nFoo:\n  Prot: 1\n  Port: 2"
+
+	var unknown []string
+	err := Parse(strings.NewReader(input), flagSet, OnUnknown(func(err *UnknownKeyError) {
+		unknown = append(unknown, err.Key)
+	}))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if strings.Join(unknown, ",") != "Other,Foo.Prot" {
+		t.Error("unexpected unknown keys:", unknown)
+	}
+	if v := flagSet.Lookup("Foo.Port").Value.String(); v != "2" {
+		t.Error("parsing did not continue past unknown keys, got", v)
+	}
+}